@@ -0,0 +1,211 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/leaderboard"
+	"github.com/google/pullsheet/pkg/period"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+var (
+	// archiveCmd represents the subcommand for `pullsheet archive`
+	archiveCmd = &cobra.Command{
+		Use:           "archive",
+		Short:         "Generate a leaderboard for each month or quarter into a directory",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArchive(rootOpts)
+		},
+	}
+
+	archiveDir   string
+	archiveEvery string
+)
+
+func init() {
+	archiveCmd.Flags().StringVar(
+		&archiveDir,
+		"out-dir",
+		"archive",
+		"Directory to write the leaderboards and their overview to",
+	)
+
+	archiveCmd.Flags().StringVar(
+		&archiveEvery,
+		"every",
+		"month",
+		fmt.Sprintf("Length of the period of each leaderboard: %s", strings.Join(period.Units, " or ")),
+	)
+
+	archiveCmd.Flags().StringSliceVar(
+		&jsonFiles,
+		"json-files",
+		[]string{},
+		"List of JSON files to append to the results, such as those of earlier periods",
+	)
+
+	archiveCmd.Flags().StringVar(
+		&sinceDisplay,
+		"since-display",
+		"",
+		"This overrides the start of the first period, primary used if appending past JSON files",
+	)
+
+	archiveCmd.Flags().StringVar(
+		&untilDisplay,
+		"until-display",
+		"",
+		"This overrides the end of the last period, primary used if appending past JSON files",
+	)
+
+	rootCmd.AddCommand(archiveCmd)
+}
+
+func runArchive(rootOpts *rootOptions) (err error) {
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { finish(err) }()
+
+	sinceParsedDisplay, err = stringToTime(sinceDisplay, rootOpts.sinceParsed)
+	if err != nil {
+		return err
+	}
+	untilParsedDisplay, err = stringToTime(untilDisplay, rootOpts.untilParsed)
+	if err != nil {
+		return err
+	}
+
+	periods, err := period.Range(sinceParsedDisplay, untilParsedDisplay, archiveEvery)
+	if err != nil {
+		return err
+	}
+
+	d, err := dataFromGitHub(ctx, rootOpts)
+	md := metadata(ctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return err
+	}
+
+	d, err = appendJSONFiles(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		return err
+	}
+
+	title := rootOpts.title
+	if title == "" {
+		title = strings.Join(rootOpts.repos, ", ")
+	}
+
+	overview := []leaderboard.PeriodWinners{}
+	for i, p := range periods {
+		pd := periodData(d, p)
+
+		// The first and last periods may only be partly covered
+		since, until := p.Since, p.Last()
+		if since.Before(sinceParsedDisplay) {
+			since = sinceParsedDisplay
+		}
+		if until.After(untilParsedDisplay) {
+			until = untilParsedDisplay
+		}
+
+		links := []leaderboard.Link{{Name: "All periods", Href: "index.html"}}
+		if i > 0 {
+			links = append(links, leaderboard.Link{Name: "&larr; " + periods[i-1].Name, Href: periods[i-1].Name + ".html"})
+		}
+		if i < len(periods)-1 {
+			links = append(links, leaderboard.Link{Name: periods[i+1].Name + " &rarr;", Href: periods[i+1].Name + ".html"})
+		}
+
+		out, err := leaderboard.Render(leaderboard.Options{
+			Title:          fmt.Sprintf("%s: %s", title, p.Name),
+			Since:          since,
+			Until:          until,
+			DisableCaching: disableCaching,
+			Warnings:       warnings(md),
+			Links:          links,
+		}, rootOpts.users, pd.PRs, pd.Reviews, pd.Issues, pd.Comments)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(archiveDir, p.Name+".html"), []byte(out), 0o644); err != nil {
+			return err
+		}
+
+		// Newest first
+		overview = append([]leaderboard.PeriodWinners{{
+			Title:   p.Name,
+			Href:    p.Name + ".html",
+			Since:   since,
+			Until:   until,
+			Winners: leaderboard.Winners(rootOpts.users, pd.PRs, pd.Reviews, pd.Issues, pd.Comments),
+		}}, overview...)
+	}
+
+	out, err := leaderboard.RenderWinners(title, overview)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(archiveDir, "index.html"), []byte(out), 0o644); err != nil {
+		return err
+	}
+	klog.Infof("wrote %d leaderboards to %s", len(periods), archiveDir)
+
+	return incompleteError(md, rootOpts)
+}
+
+// periodData returns the data dated within a period
+func periodData(d *data, p period.Period) *data {
+	return &data{
+		PRs:      datedIn(d.PRs, p, func(s *repo.PRSummary) string { return s.Date }),
+		Reviews:  datedIn(d.Reviews, p, func(s *repo.ReviewSummary) string { return s.Date }),
+		Issues:   datedIn(d.Issues, p, func(s *repo.IssueSummary) string { return s.Date }),
+		Comments: datedIn(d.Comments, p, func(s *repo.CommentSummary) string { return s.Date }),
+	}
+}
+
+// datedIn returns the summaries dated within a period
+func datedIn[T any](sums []T, p period.Period, date func(T) string) []T {
+	from := p.Since.Format(dateForm)
+	to := p.Until.Format(dateForm)
+
+	result := []T{}
+	for _, s := range sums {
+		if d := date(s); d >= from && d < to {
+			result = append(result, s)
+		}
+	}
+	return result
+}
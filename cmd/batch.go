@@ -0,0 +1,311 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/leaderboard"
+	"github.com/google/pullsheet/pkg/period"
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/pullsheet"
+	"github.com/google/pullsheet/pkg/summary"
+)
+
+var (
+	// batchCmd represents the subcommand for `pullsheet batch`
+	batchCmd = &cobra.Command{
+		Use:           "batch",
+		Short:         "Generate every report defined in the config file into a directory",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBatch(rootOpts)
+		},
+	}
+
+	outDir string
+)
+
+// reportKeys are the settings a report in the config file may hold, named as their flags
+var reportKeys = map[string]bool{
+	"org": true, "repos": true, "branches": true, "users": true, "teams": true, "since": true, "until": true,
+	"title": true, "include-bots": true, "period": true,
+}
+
+// reportName is the pattern report names must match, as they name output directories
+var reportName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// batchReport is a report defined in the config file
+type batchReport struct {
+	Name string
+	pullsheet.Report
+}
+
+func init() {
+	batchCmd.Flags().StringVar(
+		&outDir,
+		"out-dir",
+		"site",
+		"Directory to write the reports and their index.html to",
+	)
+
+	rootCmd.AddCommand(batchCmd)
+}
+
+func runBatch(rootOpts *rootOptions) error {
+	reports, err := loadReports(rootOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	c, err := client.New(ctx, clientConfig(rootOpts))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	entries := []leaderboard.IndexEntry{}
+	failed := 0
+	for _, r := range reports {
+		if ctx.Err() != nil {
+			break
+		}
+
+		md, err := writeReport(ctx, c, r, rootOpts)
+		if err != nil {
+			if !rootOpts.keepGoing {
+				return fmt.Errorf("report %s: %w", r.Name, err)
+			}
+			klog.Errorf("report %s failed, continuing with the remaining reports: %v", r.Name, err)
+		}
+		if err != nil || md != nil {
+			failed++
+		}
+
+		entries = append(entries, indexEntry(r, md, err))
+	}
+
+	index, err := leaderboard.RenderIndex("Reports", entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "index.html"), []byte(index), 0o644); err != nil {
+		return err
+	}
+	klog.Infof("wrote %d reports to %s", len(entries), outDir)
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", context.Cause(ctx), errIncomplete)
+	}
+	if failed > 0 && rootOpts.failOnErrors {
+		return fmt.Errorf("%d reports have missing data: %w", failed, errIncomplete)
+	}
+	return nil
+}
+
+// loadReports returns the reports defined in the config file, sorted by name. Settings a report does not hold are
+// taken from the flags.
+func loadReports(rootOpts *rootOptions) ([]*batchReport, error) {
+	path, err := configPath(rootOpts.config)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("batch requires --config, or a %s in the current directory or user config directory", configName)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read %s: %v", path, err)
+	}
+
+	names := []string{}
+	for name := range v.GetStringMap("reports") {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no reports defined in %s", path)
+	}
+	sort.Strings(names)
+
+	reports := []*batchReport{}
+	for _, name := range names {
+		r, err := loadReport(v, name, rootOpts)
+		if err != nil {
+			return nil, fmt.Errorf("report %q in %s: %v", name, path, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// loadReport returns a report defined in a config file
+func loadReport(v *viper.Viper, name string, rootOpts *rootOptions) (*batchReport, error) {
+	if !reportName.MatchString(name) {
+		return nil, fmt.Errorf("name must match %s", reportName)
+	}
+
+	sub := v.Sub("reports." + name)
+	if sub == nil {
+		return nil, fmt.Errorf("no settings")
+	}
+	for key := range sub.AllSettings() {
+		if !reportKeys[key] {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	users, err := teamUsers(v, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &batchReport{Name: name, Report: pullsheet.Report{
+		Org:         sub.GetString("org"),
+		Repos:       sub.GetStringSlice("repos"),
+		Users:       users,
+		Branches:    rootOpts.branches,
+		Since:       rootOpts.sinceParsed,
+		Until:       rootOpts.untilParsed,
+		IncludeBots: rootOpts.includeBots,
+		Location:    rootOpts.location,
+		Title:       sub.GetString("title"),
+	}}
+
+	if r.Org == "" && len(r.Repos) == 0 {
+		return nil, fmt.Errorf("org or repos must be set")
+	}
+	if sub.IsSet("branches") {
+		r.Branches = sub.GetStringSlice("branches")
+	}
+	if sub.IsSet("include-bots") {
+		r.IncludeBots = sub.GetBool("include-bots")
+	}
+	if sub.IsSet("period") {
+		fyStart, err := period.ParseMonth(rootOpts.fyStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fiscal-year-start: %v", err)
+		}
+		p, err := period.Parse(sub.GetString("period"), time.Now().In(rootOpts.location), fyStart)
+		if err != nil {
+			return nil, err
+		}
+		r.Since, r.Until = p.Since, p.Until.Add(-time.Nanosecond)
+		return r, nil
+	}
+
+	if r.Since, err = stringToTime(sub.GetString("since"), r.Since); err != nil {
+		return nil, fmt.Errorf("since: %v", err)
+	}
+	if r.Until, err = stringToTime(sub.GetString("until"), r.Until); err != nil {
+		return nil, fmt.Errorf("until: %v", err)
+	}
+
+	return r, nil
+}
+
+// writeReport writes the leaderboard, CSV and JSON files of a report into its directory, returning the metadata of
+// its data
+func writeReport(ctx context.Context, c *client.Client, r *batchReport, rootOpts *rootOptions) (*print.Metadata, error) {
+	klog.Infof("generating report %s", r.Name)
+
+	// Failures are collected per report, so that each report lists its own
+	rctx := ctx
+	if rootOpts.keepGoing {
+		rctx = summary.WithErrors(ctx, &summary.Errors{})
+	}
+
+	gathered, err := r.Data(rctx, c)
+	md := metadata(rctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return nil, err
+	}
+
+	dir := filepath.Join(outDir, r.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	html, err := r.RenderWithWarnings(gathered, warnings(md))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{"index.html": html}
+
+	// The JSON file can be passed to leaderboard --json-files
+	d := &data{Metadata: md, PRs: gathered.PRs, Reviews: gathered.Reviews, Issues: gathered.Issues, Comments: gathered.Comments}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	files["data.json"] = string(b)
+
+	csvs := map[string]interface{}{"prs.csv": d.PRs, "reviews.csv": d.Reviews, "issues.csv": d.Issues, "comments.csv": d.Comments}
+	for name, summaries := range csvs {
+		out, err := print.Marshal(summaries, "CSV", print.Options{Metadata: md})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		files[name] = out
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+// indexEntry returns the index entry of a report, linking to its files unless it failed with err
+func indexEntry(r *batchReport, md *print.Metadata, err error) leaderboard.IndexEntry {
+	e := leaderboard.IndexEntry{
+		Title:    r.DisplayTitle(),
+		Since:    r.Since,
+		Until:    r.Until,
+		Warnings: warnings(md),
+	}
+	if err != nil {
+		e.Links = []leaderboard.Link{{Name: "failed", Href: "#"}}
+		e.Warnings = append(e.Warnings, fmt.Sprintf("This report failed: %v", err))
+		return e
+	}
+
+	e.Links = []leaderboard.Link{{Name: "leaderboard", Href: r.Name + "/index.html"}}
+	for _, name := range []string{"prs.csv", "reviews.csv", "issues.csv", "comments.csv", "data.json"} {
+		e.Links = append(e.Links, leaderboard.Link{Name: name, Href: r.Name + "/" + name})
+	}
+	return e
+}
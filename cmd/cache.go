@@ -0,0 +1,384 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/google/pullsheet/pkg/ghcache"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+var (
+	// cacheCmd represents the subcommand for `pullsheet cache`
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and manage the cache of GitHub data",
+	}
+
+	cacheStatsCmd = &cobra.Command{
+		Use:           "stats",
+		Short:         "Show entries, sizes and ages of cached data by type",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheStats()
+		},
+	}
+
+	cachePurgeCmd = &cobra.Command{
+		Use:           "purge",
+		Short:         "Remove cached data by repository, type prefix or age",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCachePurge(rootOpts)
+		},
+	}
+
+	cacheExportCmd = &cobra.Command{
+		Use:           "export <file>",
+		Short:         "Write cached data to a .tar.gz file, to be imported on another machine",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheExport(rootOpts, args[0])
+		},
+	}
+
+	cacheImportCmd = &cobra.Command{
+		Use:           "import <file>",
+		Short:         "Read cached data exported on another machine",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheImport(args[0])
+		},
+	}
+
+	cachePrefixes  []string
+	cacheOlderThan time.Duration
+	cacheCorrupt   bool
+	cacheAll       bool
+	cacheDryRun    bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{cachePurgeCmd, cacheExportCmd} {
+		c.Flags().StringSliceVar(
+			&cachePrefixes,
+			"prefix",
+			[]string{},
+			"comma-delimited list of key prefixes to match. ex: pr-listfiles-,issue-comments-",
+		)
+
+		c.Flags().DurationVar(
+			&cacheOlderThan,
+			"older-than",
+			0,
+			"only match entries older than this. ex: 720h",
+		)
+	}
+
+	cachePurgeCmd.Flags().BoolVar(
+		&cacheCorrupt,
+		"corrupt",
+		false,
+		"only match entries which cannot be decoded")
+
+	cachePurgeCmd.Flags().BoolVar(
+		&cacheAll,
+		"all",
+		false,
+		"purge every entry if no other filter is given")
+
+	cachePurgeCmd.Flags().BoolVar(
+		&cacheDryRun,
+		"dry-run",
+		false,
+		"list the entries that would be purged without removing them")
+
+	cacheCmd.AddCommand(cacheStatsCmd, cachePurgeCmd, cacheExportCmd, cacheImportCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// cacheDirs returns the directory of the disk cache, and that of responses stored for conditional requests
+func cacheDirs() (string, string, error) {
+	dir, err := ghcache.DiskDir("", "")
+	if err != nil {
+		return "", "", err
+	}
+	httpDir, err := ghcache.HTTPDir()
+	return dir, httpDir, err
+}
+
+// cacheEntries returns the entries of the disk cache and the stored responses
+func cacheEntries(dir string, httpDir string) ([]ghcache.Entry, error) {
+	es, err := ghcache.DiskEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	hes, err := ghcache.HTTPEntries(httpDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(es, hes...), nil
+}
+
+// matchingEntries returns the cache entries matching the repository, prefix, age and corruption filters
+func matchingEntries(dir string, httpDir string, rootOpts *rootOptions) ([]ghcache.Entry, error) {
+	es, err := cacheEntries(dir, httpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	type orgProject struct{ org, project string }
+	repos := []orgProject{}
+	for _, r := range rootOpts.repos {
+		org, project := repo.ParseURL(r)
+		repos = append(repos, orgProject{org, project})
+	}
+
+	matched := []ghcache.Entry{}
+	for _, e := range es {
+		if len(repos) > 0 {
+			found := false
+			for _, r := range repos {
+				if e.InRepo(r.org, r.project) {
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		if len(cachePrefixes) > 0 {
+			found := false
+			for _, p := range cachePrefixes {
+				if strings.HasPrefix(e.Key, p) {
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		if cacheOlderThan > 0 && time.Since(e.Created) < cacheOlderThan {
+			continue
+		}
+
+		if cacheCorrupt && e.Err == nil {
+			continue
+		}
+
+		matched = append(matched, e)
+	}
+
+	return matched, nil
+}
+
+func runCacheStats() error {
+	dir, httpDir, err := cacheDirs()
+	if err != nil {
+		return err
+	}
+
+	es, err := cacheEntries(dir, httpDir)
+	if err != nil {
+		return err
+	}
+
+	type stats struct {
+		entries int
+		size    int64
+		oldest  time.Time
+		newest  time.Time
+		corrupt []string
+	}
+
+	byType := map[string]*stats{}
+	total := &stats{}
+	for _, e := range es {
+		for _, s := range []*stats{byType[e.Type], total} {
+			if s == nil {
+				s = &stats{}
+				byType[e.Type] = s
+			}
+			s.entries++
+			s.size += e.Size
+			if e.Err != nil {
+				s.corrupt = append(s.corrupt, e.Key)
+				continue
+			}
+			if s.oldest.IsZero() || e.Created.Before(s.oldest) {
+				s.oldest = e.Created
+			}
+			if e.Created.After(s.newest) {
+				s.newest = e.Created
+			}
+		}
+	}
+
+	types := []string{}
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	fmt.Printf("Cache: %s\nStored responses: %s\n\n", dir, httpDir)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tENTRIES\tSIZE\tOLDEST\tNEWEST\tCORRUPT")
+	row := func(name string, s *stats) {
+		fmt.Fprintf(tw, "%s-\t%d\t%s\t%s\t%s\t%d\n", name, s.entries, byteSize(s.size), age(s.oldest), age(s.newest), len(s.corrupt))
+	}
+	for _, t := range types {
+		row(t, byType[t])
+	}
+	fmt.Fprintf(tw, "total\t%d\t%s\t%s\t%s\t%d\n", total.entries, byteSize(total.size), age(total.oldest), age(total.newest), len(total.corrupt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(total.corrupt) > 0 {
+		fmt.Printf("\nCorrupt entries (remove with `pullsheet cache purge --corrupt`):\n")
+		for _, k := range total.corrupt {
+			fmt.Printf("  %s\n", k)
+		}
+	}
+
+	return nil
+}
+
+func runCachePurge(rootOpts *rootOptions) error {
+	if len(rootOpts.repos) == 0 && len(cachePrefixes) == 0 && cacheOlderThan == 0 && !cacheCorrupt && !cacheAll {
+		return fmt.Errorf("refusing to purge the whole cache without --all. Filter with --repos, --prefix, --older-than or --corrupt")
+	}
+
+	dir, httpDir, err := cacheDirs()
+	if err != nil {
+		return err
+	}
+
+	es, err := matchingEntries(dir, httpDir, rootOpts)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, e := range es {
+		size += e.Size
+		if cacheDryRun {
+			fmt.Println(e.Key)
+			continue
+		}
+		if err := ghcache.DiskRemove(e); err != nil {
+			return err
+		}
+	}
+
+	verb := "Purged"
+	if cacheDryRun {
+		verb = "Would purge"
+	}
+	fmt.Printf("%s %d entries (%s) from %s and %s\n", verb, len(es), byteSize(size), dir, httpDir)
+	return nil
+}
+
+func runCacheExport(rootOpts *rootOptions, path string) error {
+	dir, httpDir, err := cacheDirs()
+	if err != nil {
+		return err
+	}
+
+	es, err := matchingEntries(dir, httpDir, rootOpts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := ghcache.DiskExport(es, f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d entries from %s and %s to %s\n", len(es), dir, httpDir, path)
+	return nil
+}
+
+func runCacheImport(path string) error {
+	dir, httpDir, err := cacheDirs()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := ghcache.DiskImport(dir, httpDir, f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d entries from %s into %s and %s\n", n, path, dir, httpDir)
+	return nil
+}
+
+// byteSize formats a number of bytes for humans
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// age formats how long ago a time was for humans
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return d.Round(time.Minute).String()
+}
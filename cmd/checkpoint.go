@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/summary"
+)
+
+// checkpointContext returns a context in which the run records checkpoints, resuming an earlier run if requested.
+// finish must be called with the outcome of the run: the checkpoint is removed on success, and kept otherwise.
+func checkpointContext(ctx context.Context, rootOpts *rootOptions) (context.Context, func(error), error) {
+	dir := rootOpts.checkpointDir
+	if dir == "" {
+		root, err := os.UserCacheDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("cache dir: %w", err)
+		}
+		dir = filepath.Join(root, "pullsheet", "runs")
+	}
+
+	settings := summary.RunSettings{
+		Window:      fmt.Sprintf("since=%q until=%q period=%q timezone=%q fiscal-year-start=%q", rootOpts.since, rootOpts.until, rootOpts.period, rootOpts.timezone, rootOpts.fyStart),
+		Users:       rootOpts.users,
+		Branches:    rootOpts.branches,
+		IncludeBots: rootOpts.includeBots,
+	}
+
+	var cp *summary.Checkpoint
+	var err error
+	if rootOpts.resume != "" {
+		cp, err = summary.LoadCheckpoint(dir, rootOpts.resume, settings)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Relative windows such as now-90d must not move when resuming
+		rootOpts.sinceParsed, rootOpts.untilParsed = cp.Window()
+	} else {
+		cp, err = summary.NewCheckpoint(dir, rootOpts.sinceParsed, rootOpts.untilParsed, settings)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	finish := func(err error) {
+		if err != nil {
+			if err := cp.Save(); err != nil {
+				klog.Warningf("saving checkpoint for run %s: %v", cp.ID, err)
+			}
+			fmt.Fprintf(os.Stderr, "run %s did not complete, continue it with: --resume %s\n", cp.ID, cp.ID)
+			return
+		}
+		if err := cp.Remove(); err != nil {
+			klog.Warningf("removing checkpoint for run %s: %v", cp.ID, err)
+		}
+	}
+
+	return summary.WithCheckpoint(ctx, cp), finish, nil
+}
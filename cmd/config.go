@@ -0,0 +1,137 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+)
+
+// configName is the name of the config file holding report profiles
+const configName = "pullsheet.yaml"
+
+// profileKeys are the settings a profile may hold, named as their flags. "teams" names teams whose members are
+// added to "users".
+var profileKeys = map[string]bool{
+	"org": true, "repos": true, "branches": true, "users": true, "teams": true, "since": true, "until": true,
+	"title": true, "token-path": true, "out": true, "include-bots": true, "strategy": true, "backend": true,
+	"incremental-state": true, "checkpoint-dir": true, "timeout": true, "keep-going": true,
+	"fail-on-repo-errors": true, "progress": true, "period": true, "timezone": true, "fiscal-year-start": true,
+	"db": true, "parquet-dir": true, "columns": true, "exclude-columns": true, "sort": true, "flatten": true,
+	"delimiter": true, "bom": true, "csv-comments": true, "http-cache": true,
+}
+
+// configPath returns the config file to read profiles from: the given path, or pullsheet.yaml in the current
+// directory or the user config directory. It returns "" if there is none.
+func configPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	candidates := []string{configName}
+	if dir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "pullsheet", configName))
+	}
+
+	for _, c := range candidates {
+		_, err := os.Stat(c)
+		if err == nil {
+			return c, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// applyProfile makes the settings of a named profile the defaults for this run. Flags and environment variables
+// still take precedence over them.
+func applyProfile(path string, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	path, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("profile %q requested, but there is no %s in the current directory or user config directory", name, configName)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("read %s: %v", path, err)
+	}
+
+	if !v.IsSet("profiles." + name) {
+		return fmt.Errorf("no profile %q in %s. Profiles: %s", name, path, strings.Join(profileNames(v), ", "))
+	}
+
+	sub := v.Sub("profiles." + name)
+	profile := sub.AllSettings()
+	for key := range profile {
+		if !profileKeys[key] {
+			return fmt.Errorf("profile %q in %s: unknown setting %q", name, path, key)
+		}
+	}
+
+	if sub.IsSet("teams") {
+		users, err := teamUsers(v, sub)
+		if err != nil {
+			return fmt.Errorf("profile %q in %s: %v", name, path, err)
+		}
+		profile["users"] = users
+		delete(profile, "teams")
+	}
+
+	klog.Infof("using profile %q from %s", name, path)
+	return viper.MergeConfigMap(profile)
+}
+
+// teamUsers returns the users of a profile or report, along with the members of its teams, as defined at the top
+// level of the config file
+func teamUsers(v *viper.Viper, sub *viper.Viper) ([]string, error) {
+	users := sub.GetStringSlice("users")
+	known := v.GetStringMapStringSlice("teams")
+	for _, t := range sub.GetStringSlice("teams") {
+		members, ok := known[strings.ToLower(t)]
+		if !ok {
+			return nil, fmt.Errorf("unknown team %q", t)
+		}
+		users = append(users, members...)
+	}
+	return users, nil
+}
+
+// profileNames returns the names of the profiles in a config file
+func profileNames(v *viper.Viper) []string {
+	names := []string{}
+	for name := range v.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/repo"
+	"github.com/google/pullsheet/pkg/sqlite"
+	"github.com/google/pullsheet/pkg/summary"
+)
+
+// errIncomplete is returned once the partial results of an interrupted run, or of a run which skipped failed
+// repositories, have been written
+var errIncomplete = errors.New("results are incomplete")
+
+// runContext returns the context for a run, which is cancelled on SIGINT or SIGTERM, or once --timeout has passed.
+// A second signal exits immediately. With --keep-going, the failures of repositories are collected in the context,
+// and with --progress, progress is reported until the returned function is called.
+func runContext(rootOpts *rootOptions) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if rootOpts.keepGoing {
+		ctx = summary.WithErrors(ctx, &summary.Errors{})
+	}
+
+	stopProgress := func() {}
+	if rootOpts.progress {
+		p := summary.NewProgress()
+		ctx = summary.WithProgress(ctx, p)
+		stopProgress = p.Start(os.Stderr, 30*time.Second)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			fmt.Fprintf(os.Stderr, "%v: writing the results collected so far, repeat to exit immediately\n", sig)
+			cancel(fmt.Errorf("interrupted by %v", sig))
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+
+	if rootOpts.timeout <= 0 {
+		return ctx, func() {
+			stopProgress()
+			cancel(nil)
+		}
+	}
+
+	tctx, tcancel := context.WithTimeoutCause(ctx, rootOpts.timeout, fmt.Errorf("timed out after %s", rootOpts.timeout))
+	return tctx, func() {
+		stopProgress()
+		tcancel()
+		cancel(nil)
+	}
+}
+
+// streamContext returns a context in which, with --out NDJSON, the summaries of each repository are written to
+// standard output as soon as they are collected. Incremental runs are written once complete, as they merge results.
+func streamContext(ctx context.Context, rootOpts *rootOptions) context.Context {
+	if rootOpts.out != "NDJSON" || rootOpts.stateFile != "" {
+		return ctx
+	}
+
+	return summary.WithStream(ctx, summary.NewStream(func(_ string, summaries interface{}) error {
+		w := bufio.NewWriter(os.Stdout)
+		if err := print.WriteNDJSON(w, summaries); err != nil {
+			return err
+		}
+		return w.Flush()
+	}))
+}
+
+// metadata returns output metadata describing whether err was caused by the run being interrupted, and which
+// repositories were skipped by --keep-going. It returns nil if there is nothing to report.
+func metadata(ctx context.Context, err error) *print.Metadata {
+	md := &print.Metadata{}
+	if err != nil && ctx.Err() != nil {
+		md.Incomplete = true
+		md.Reason = context.Cause(ctx).Error()
+	}
+
+	if e := summary.ErrorsFrom(ctx); e != nil {
+		for _, re := range e.List() {
+			md.Errors = append(md.Errors, print.RepoError{Repo: re.Repo, Kind: re.Kind, Error: re.Error})
+		}
+	}
+
+	if !md.Incomplete && len(md.Errors) == 0 {
+		return nil
+	}
+	return md
+}
+
+// printResults prints the results of a run along with their metadata. If the run was interrupted, the results
+// collected so far are printed before an error is returned; other errors are returned without printing anything.
+func printResults(ctx context.Context, data interface{}, err error, rootOpts *rootOptions) error {
+	md := metadata(ctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return err
+	}
+
+	// Streamed results have already been written
+	if summary.StreamFrom(ctx) != nil {
+		if md != nil {
+			md.Log()
+		}
+		return incompleteError(md, rootOpts)
+	}
+
+	if rootOpts.out == "SQLite" {
+		d, err := sqliteData(data)
+		if err != nil {
+			return err
+		}
+		if err := writeDB(ctx, d, md, rootOpts); err != nil {
+			return err
+		}
+		return incompleteError(md, rootOpts)
+	}
+
+	if rootOpts.out == "Parquet" && rootOpts.parquetDir != "" {
+		if err := writeParquetDir(data, md, rootOpts); err != nil {
+			return err
+		}
+		return incompleteError(md, rootOpts)
+	}
+
+	// JSON output has the same shape whether or not the run completed
+	opts := print.Options{Metadata: md, CSV: rootOpts.csv}
+	if md == nil && rootOpts.out == "JSON" {
+		opts.Metadata = &print.Metadata{}
+	}
+
+	if err := print.Print(data, rootOpts.out, opts); err != nil {
+		return err
+	}
+
+	return incompleteError(md, rootOpts)
+}
+
+// writeParquetDir writes summaries into the --parquet-dir directory, partitioned by project and month
+func writeParquetDir(data interface{}, md *print.Metadata, rootOpts *rootOptions) error {
+	files, err := print.WriteParquetDir(rootOpts.parquetDir, data)
+	if err != nil {
+		return err
+	}
+	if md != nil {
+		md.Log()
+	}
+
+	klog.Infof("wrote %d Parquet files to %s", len(files), rootOpts.parquetDir)
+	return nil
+}
+
+// sqliteData returns summaries, such as those of a prs run, as data to write to a database
+func sqliteData(data interface{}) (sqlite.Data, error) {
+	switch v := data.(type) {
+	case []*repo.PRSummary:
+		return sqlite.Data{PRs: v}, nil
+	case []*repo.ReviewSummary:
+		return sqlite.Data{Reviews: v}, nil
+	case []*repo.IssueSummary:
+		return sqlite.Data{Issues: v}, nil
+	case []*repo.CommentSummary:
+		return sqlite.Data{Comments: v}, nil
+	}
+	return sqlite.Data{}, fmt.Errorf("%T cannot be written to SQLite, only pull request, review, issue and comment summaries", data)
+}
+
+// writeDB records a run and its results, with the given metadata, in the --db database
+func writeDB(ctx context.Context, d sqlite.Data, md *print.Metadata, rootOpts *rootOptions) error {
+	run := sqlite.Run{
+		Command:  commandLine(),
+		Repos:    rootOpts.repos,
+		Users:    rootOpts.users,
+		Branches: rootOpts.branches,
+		Since:    rootOpts.sinceParsed,
+		Until:    rootOpts.untilParsed,
+		Location: rootOpts.location,
+	}
+	if md != nil {
+		run.Incomplete = md.Incomplete
+		run.Reason = md.Reason
+		for _, e := range md.Errors {
+			run.Errors = append(run.Errors, fmt.Sprintf("%s for %s: %s", e.Kind, e.Repo, e.Error))
+		}
+	}
+
+	// The results are written even if the run was interrupted
+	_, err := sqlite.Write(context.WithoutCancel(ctx), rootOpts.db, run, d)
+	return err
+}
+
+// incompleteError returns the error to exit with after writing results with the given metadata
+func incompleteError(md *print.Metadata, rootOpts *rootOptions) error {
+	switch {
+	case md == nil:
+		return nil
+	case md.Incomplete:
+		return fmt.Errorf("%s: %w", md.Reason, errIncomplete)
+	case rootOpts.failOnErrors:
+		return fmt.Errorf("%d repositories failed: %w", len(md.Errors), errIncomplete)
+	}
+	return nil
+}
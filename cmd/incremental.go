@@ -0,0 +1,136 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/repo"
+	"github.com/google/pullsheet/pkg/summary"
+)
+
+// state is the results of incremental runs, along with what each data set covers
+type state struct {
+	data
+	Sync map[string]*summary.Sync `json:",omitempty"`
+}
+
+// loadState reads the results of previous incremental runs. Data sets without sync state are fetched in full.
+func loadState(path string) (*state, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		klog.Infof("%s does not exist yet, fetching everything", path)
+		return &state{Sync: map[string]*summary.Sync{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+	if st.Sync == nil {
+		st.Sync = map[string]*summary.Sync{}
+	}
+	return &st, nil
+}
+
+// saveState writes the results of an incremental run for the next one to build on, then the watermarks of the
+// repositories it synced. Results are saved even if the run failed partway, in which case its error is returned.
+func saveState(c *client.Client, path string, st *state, sync *summary.Sync, runErr error) error {
+	b, err := json.Marshal(st)
+	if err == nil {
+		err = os.WriteFile(path, b, 0o644)
+	}
+	if err == nil {
+		err = sync.Save(c)
+	}
+
+	if runErr != nil {
+		if err != nil {
+			klog.Errorf("save %s: %v", path, err)
+		}
+		return runErr
+	}
+	return err
+}
+
+// pulls returns pull request summaries, updating the incremental state file if one is configured
+func pulls(ctx context.Context, c *client.Client, rootOpts *rootOptions, repos []string) ([]*repo.PRSummary, error) {
+	if rootOpts.stateFile == "" {
+		return summary.Pulls(ctx, c, repos, rootOpts.users, rootOpts.branches, rootOpts.sinceParsed, rootOpts.untilParsed)
+	}
+
+	st, err := loadState(rootOpts.stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	st.PRs, st.Sync["prs"], err = summary.IncrementalPulls(ctx, c, st.PRs, st.Sync["prs"], repos, rootOpts.users, rootOpts.branches, rootOpts.sinceParsed, rootOpts.untilParsed)
+	return st.PRs, saveState(c, rootOpts.stateFile, st, st.Sync["prs"], err)
+}
+
+// reviews returns review summaries, updating the incremental state file if one is configured
+func reviews(ctx context.Context, c *client.Client, rootOpts *rootOptions, repos []string) ([]*repo.ReviewSummary, error) {
+	if rootOpts.stateFile == "" {
+		return summary.Reviews(ctx, c, repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	}
+
+	st, err := loadState(rootOpts.stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	st.Reviews, st.Sync["reviews"], err = summary.IncrementalReviews(ctx, c, st.Reviews, st.Sync["reviews"], repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	return st.Reviews, saveState(c, rootOpts.stateFile, st, st.Sync["reviews"], err)
+}
+
+// issues returns closed issue summaries, updating the incremental state file if one is configured
+func issues(ctx context.Context, c *client.Client, rootOpts *rootOptions, repos []string) ([]*repo.IssueSummary, error) {
+	if rootOpts.stateFile == "" {
+		return summary.Issues(ctx, c, repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	}
+
+	st, err := loadState(rootOpts.stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	st.Issues, st.Sync["issues"], err = summary.IncrementalIssues(ctx, c, st.Issues, st.Sync["issues"], repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	return st.Issues, saveState(c, rootOpts.stateFile, st, st.Sync["issues"], err)
+}
+
+// comments returns issue comment summaries, updating the incremental state file if one is configured
+func comments(ctx context.Context, c *client.Client, rootOpts *rootOptions, repos []string) ([]*repo.CommentSummary, error) {
+	if rootOpts.stateFile == "" {
+		return summary.Comments(ctx, c, repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	}
+
+	st, err := loadState(rootOpts.stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	st.Comments, st.Sync["comments"], err = summary.IncrementalComments(ctx, c, st.Comments, st.Sync["comments"], repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	return st.Comments, saveState(c, rootOpts.stateFile, st, st.Sync["comments"], err)
+}
@@ -15,10 +15,6 @@
 package cmd
 
 import (
-	"context"
-
-	"github.com/google/pullsheet/pkg/print"
-	"github.com/google/pullsheet/pkg/summary"
 	"github.com/spf13/cobra"
 
 	"github.com/google/pullsheet/pkg/client"
@@ -39,22 +35,22 @@ func init() {
 	rootCmd.AddCommand(issuesCommentsCmd)
 }
 
-func runIssueComments(rootOpts *rootOptions) error {
-	ctx := context.Background()
-	c, err := client.New(ctx, client.Config{GitHubTokenPath: rootOpts.tokenPath})
-	if err != nil {
-		return err
-	}
+func runIssueComments(rootOpts *rootOptions) (err error) {
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
 
-	data, err := summary.Comments(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
 	if err != nil {
 		return err
 	}
+	defer func() { finish(err) }()
+	ctx = streamContext(ctx, rootOpts)
 
-	err = print.Print(data, rootOpts.out)
+	c, err := client.New(ctx, clientConfig(rootOpts))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	data, err := comments(ctx, c, rootOpts, rootOpts.repos)
+	return printResults(ctx, data, err, rootOpts)
 }
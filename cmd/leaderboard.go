@@ -23,21 +23,23 @@ import (
 	"time"
 
 	"github.com/google/pullsheet/pkg/repo"
-	"github.com/google/pullsheet/pkg/summary"
-	"github.com/karrick/tparse"
 	"k8s.io/klog/v2"
 
 	"github.com/spf13/cobra"
 
 	"github.com/google/pullsheet/pkg/client"
 	"github.com/google/pullsheet/pkg/leaderboard"
+	"github.com/google/pullsheet/pkg/period"
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/sqlite"
+	"github.com/google/pullsheet/pkg/summary"
 )
 
 var (
 	// leaderBoardCmd represents the subcommand for `pullsheet leaderboard`
 	leaderBoardCmd = &cobra.Command{
 		Use:           "leaderboard",
-		Short:         "Generate leaderboard data",
+		Short:         "Generate leaderboard data, as HTML or with --out Markdown, XLSX, Parquet or SQLite",
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -53,9 +55,14 @@ var (
 	untilDisplay       string
 	sinceParsedDisplay time.Time
 	untilParsedDisplay time.Time
+	trend              string
+	comparePrevious    bool
+	compareSince       string
+	compareUntil       string
 )
 
 type data struct {
+	Metadata *print.Metadata `json:",omitempty"`
 	PRs      []*repo.PRSummary
 	Reviews  []*repo.ReviewSummary
 	Issues   []*repo.IssueSummary
@@ -103,6 +110,34 @@ func init() {
 		"This overrides the until date displayed on the leaderboard, primary used if appending past JSON files",
 	)
 
+	leaderBoardCmd.Flags().StringVar(
+		&trend,
+		"trend",
+		"",
+		"Show the trend of the top contributors of each chart by day, week or month",
+	)
+
+	leaderBoardCmd.Flags().BoolVar(
+		&comparePrevious,
+		"compare-previous",
+		false,
+		"Compare with the window of the same length immediately before, such as the previous quarter",
+	)
+
+	leaderBoardCmd.Flags().StringVar(
+		&compareSince,
+		"compare-since",
+		"",
+		"Compare with the window starting at this date or duration, in place of the previous window",
+	)
+
+	leaderBoardCmd.Flags().StringVar(
+		&compareUntil,
+		"compare-until",
+		"",
+		"End of the window to compare with, if --compare-since is set (date or duration)",
+	)
+
 	rootCmd.AddCommand(leaderBoardCmd)
 }
 
@@ -110,16 +145,24 @@ func stringToTime(s string, root time.Time) (time.Time, error) {
 	if s == "" {
 		return root, nil
 	}
-	parsed, err := tparse.ParseNow(dateForm, s)
-	if err != nil {
-		klog.Infof("%q not a duration: %v", s, err)
-		return time.Time{}, err
-	}
-	return parsed, nil
+	return parseTime(s, time.Now().In(rootOpts.location))
 }
 
-func runLeaderBoard(rootOpts *rootOptions) error {
-	var err error
+func runLeaderBoard(rootOpts *rootOptions) (err error) {
+	if rootOpts.out == "Parquet" && rootOpts.parquetDir == "" {
+		return fmt.Errorf("leaderboard --out Parquet requires --parquet-dir, as it writes a file per kind of summary")
+	}
+
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	// The comparison window is not part of the run's checkpoint
+	runCtx := ctx
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { finish(err) }()
 
 	sinceParsedDisplay, err = stringToTime(sinceDisplay, rootOpts.sinceParsed)
 	if err != nil {
@@ -130,10 +173,18 @@ func runLeaderBoard(rootOpts *rootOptions) error {
 		return err
 	}
 
-	d, err := dataFromGitHub()
+	d, err := dataFromGitHub(ctx, rootOpts)
+	md := metadata(ctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return err
+	}
+	d.Metadata = md
+
+	cmp, cmpWarnings, err := comparison(runCtx, rootOpts, md)
 	if err != nil {
 		return err
 	}
+
 	d, err = appendJSONFiles(d)
 	if err != nil {
 		return err
@@ -148,13 +199,47 @@ func runLeaderBoard(rootOpts *rootOptions) error {
 		title = strings.Join(rootOpts.repos, ", ")
 	}
 
-	out, err := leaderboard.Render(leaderboard.Options{
+	if rootOpts.out == "SQLite" {
+		if err := writeDB(ctx, sqlite.Data{PRs: d.PRs, Reviews: d.Reviews, Issues: d.Issues, Comments: d.Comments}, md, rootOpts); err != nil {
+			return err
+		}
+		return incompleteError(md, rootOpts)
+	}
+
+	if rootOpts.out == "Parquet" {
+		for _, sums := range []interface{}{d.PRs, d.Reviews, d.Issues, d.Comments} {
+			if err := writeParquetDir(sums, nil, rootOpts); err != nil {
+				return err
+			}
+		}
+		if md != nil {
+			md.Log()
+		}
+		return incompleteError(md, rootOpts)
+	}
+
+	opts := leaderboard.Options{
 		Title:          title,
 		Since:          sinceParsedDisplay,
 		Until:          untilParsedDisplay,
 		DisableCaching: disableCaching,
+		Command:        commandLine(),
 		HideCommand:    hideCommand,
-	}, rootOpts.users, d.PRs, d.Reviews, d.Issues, d.Comments)
+		Warnings:       append(warnings(md), cmpWarnings...),
+		Trend:          trend,
+		Location:       rootOpts.location,
+		Compare:        cmp,
+	}
+
+	var out string
+	switch rootOpts.out {
+	case "Markdown":
+		out, err = leaderboard.RenderMarkdown(opts, rootOpts.users, d.PRs, d.Reviews, d.Issues, d.Comments)
+	case "XLSX":
+		out, err = workbook(opts, rootOpts, d)
+	default:
+		out, err = leaderboard.Render(opts, rootOpts.users, d.PRs, d.Reviews, d.Issues, d.Comments)
+	}
 	if err != nil {
 		return err
 	}
@@ -162,37 +247,126 @@ func runLeaderBoard(rootOpts *rootOptions) error {
 	klog.Infof("%d bytes of issue-comments output", len(out))
 	fmt.Print(out)
 
-	return nil
+	return incompleteError(md, rootOpts)
 }
 
-func dataFromGitHub() (*data, error) {
-	ctx := context.Background()
-	c, err := client.New(ctx, client.Config{GitHubTokenPath: rootOpts.tokenPath})
+// workbook returns an XLSX workbook of the leaderboard charts and the data behind them
+func workbook(opts leaderboard.Options, rootOpts *rootOptions, d *data) (string, error) {
+	rows, err := leaderboard.Rows(opts, rootOpts.users, d.PRs, d.Reviews, d.Issues, d.Comments)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	prs, err := summary.Pulls(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.branches, rootOpts.sinceParsed, rootOpts.untilParsed)
-	if err != nil {
-		return nil, err
+	return print.MarshalXLSX([]print.Sheet{
+		{Name: "Leaderboard", Data: rows},
+		{Name: "PRs", Data: d.PRs},
+		{Name: "Reviews", Data: d.Reviews},
+		{Name: "Issues", Data: d.Issues},
+		{Name: "Comments", Data: d.Comments},
+	}, d.Metadata)
+}
+
+// warnings returns the warnings to show on the leaderboard for data with the given metadata
+func warnings(md *print.Metadata) []string {
+	if md == nil {
+		return nil
 	}
 
-	reviews, err := summary.Reviews(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
-	if err != nil {
-		return nil, err
+	ws := []string{}
+	if md.Incomplete {
+		ws = append(ws, fmt.Sprintf("These results are incomplete (%s).", md.Reason))
 	}
 
-	issues, err := summary.Issues(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
-	if err != nil {
-		return nil, err
+	if len(md.Errors) > 0 {
+		missing := []string{}
+		for _, e := range md.Errors {
+			missing = append(missing, fmt.Sprintf("%s for %s", e.Kind, e.Repo))
+		}
+		ws = append(ws, fmt.Sprintf("Data could not be collected, and is missing from these results: %s.", strings.Join(missing, ", ")))
+	}
+	return ws
+}
+
+// comparison returns the data of the window to compare the leaderboard with, if any, along with warnings about
+// data missing from it. It is skipped if the main run, described by runMD, is incomplete, as every item would then
+// look new.
+func comparison(ctx context.Context, rootOpts *rootOptions, runMD *print.Metadata) (*leaderboard.Comparison, []string, error) {
+	if !comparePrevious && compareSince == "" {
+		return nil, nil, nil
+	}
+
+	if (runMD != nil && runMD.Incomplete) || ctx.Err() != nil {
+		klog.Warningf("skipping the comparison, as the run is incomplete")
+		return nil, []string{"No comparison is shown, as these results are incomplete."}, nil
+	}
+
+	opts := *rootOpts
+	opts.stateFile = ""
+	if compareSince != "" {
+		now := time.Now().In(rootOpts.location)
+		var err error
+		if opts.sinceParsed, err = parseTime(compareSince, now); err != nil {
+			return nil, nil, fmt.Errorf("compare-since: %v", err)
+		}
+		if opts.untilParsed, err = parseTime(compareUntil, now); err != nil {
+			return nil, nil, fmt.Errorf("compare-until: %v", err)
+		}
+	} else {
+		opts.sinceParsed, opts.untilParsed = period.Previous(rootOpts.sinceParsed, rootOpts.untilParsed)
+	}
+	klog.Infof("comparing with %s to %s", opts.sinceParsed, opts.untilParsed)
+
+	// Failures are collected separately, as they affect only the comparison
+	if rootOpts.keepGoing {
+		ctx = summary.WithErrors(ctx, &summary.Errors{})
+	}
+
+	d, err := dataFromGitHub(ctx, &opts)
+	md := metadata(ctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return nil, nil, fmt.Errorf("comparison: %w", err)
 	}
 
-	comments, err := summary.Comments(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	ws := []string{}
+	for _, w := range warnings(md) {
+		ws = append(ws, "Comparison: "+w)
+	}
+
+	return &leaderboard.Comparison{
+		Since:    opts.sinceParsed,
+		Until:    opts.untilParsed,
+		PRs:      d.PRs,
+		Reviews:  d.Reviews,
+		Issues:   d.Issues,
+		Comments: d.Comments,
+	}, ws, nil
+}
+
+// dataFromGitHub returns data fetched from GitHub. On error, the data fetched so far is returned along with it.
+func dataFromGitHub(ctx context.Context, rootOpts *rootOptions) (*data, error) {
+	d := &data{}
+	c, err := client.New(ctx, clientConfig(rootOpts))
 	if err != nil {
-		return nil, err
+		return d, err
 	}
 
-	return &data{prs, reviews, issues, comments}, nil
+	if d.PRs, err = pulls(ctx, c, rootOpts, rootOpts.repos); err != nil {
+		return d, err
+	}
+
+	if d.Reviews, err = reviews(ctx, c, rootOpts, rootOpts.repos); err != nil {
+		return d, err
+	}
+
+	if d.Issues, err = issues(ctx, c, rootOpts, rootOpts.repos); err != nil {
+		return d, err
+	}
+
+	if d.Comments, err = comments(ctx, c, rootOpts, rootOpts.repos); err != nil {
+		return d, err
+	}
+
+	return d, nil
 }
 
 func appendJSONFiles(d *data) (*data, error) {
@@ -15,11 +15,7 @@
 package cmd
 
 import (
-	"context"
-
-	"github.com/google/pullsheet/pkg/print"
 	"github.com/google/pullsheet/pkg/repo"
-	"github.com/google/pullsheet/pkg/summary"
 	"github.com/spf13/cobra"
 
 	"github.com/google/pullsheet/pkg/client"
@@ -40,9 +36,18 @@ func init() {
 	rootCmd.AddCommand(prsCmd)
 }
 
-func runPRs(rootOpts *rootOptions) error {
-	ctx := context.Background()
-	c, err := client.New(ctx, client.Config{GitHubTokenPath: rootOpts.tokenPath})
+func runPRs(rootOpts *rootOptions) (err error) {
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { finish(err) }()
+	ctx = streamContext(ctx, rootOpts)
+
+	c, err := client.New(ctx, clientConfig(rootOpts))
 	if err != nil {
 		return err
 	}
@@ -50,20 +55,14 @@ func runPRs(rootOpts *rootOptions) error {
 	var repos []string
 
 	if rootOpts.org != "" {
-		repos, _ = repo.ListRepoNames(ctx, c, rootOpts.org)
+		repos, err = repo.ListRepoNames(ctx, c, rootOpts.org)
+		if err != nil {
+			return err
+		}
 	} else {
 		repos = rootOpts.repos
 	}
 
-	data, err := summary.Pulls(ctx, c, repos, rootOpts.users, rootOpts.branches, rootOpts.sinceParsed, rootOpts.untilParsed)
-	if err != nil {
-		return err
-	}
-
-	err = print.Print(data, rootOpts.out)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	data, err := pulls(ctx, c, rootOpts, repos)
+	return printResults(ctx, data, err, rootOpts)
 }
@@ -0,0 +1,109 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/google/pullsheet/pkg/report"
+)
+
+var (
+	// reportCmd represents the subcommand for `pullsheet report`
+	reportCmd = &cobra.Command{
+		Use:           "report",
+		Short:         "Generate a report of one person's contributions in Markdown and HTML",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReport(rootOpts)
+		},
+	}
+
+	reportUser       string
+	reportHTMLOutput string
+)
+
+func init() {
+	reportCmd.Flags().StringVar(
+		&reportUser,
+		"user",
+		"",
+		"User to report on. Their totals are compared with everyone else's in the repositories",
+	)
+
+	reportCmd.Flags().StringVar(
+		&reportHTMLOutput,
+		"html-output",
+		"",
+		"Filepath to write the report as HTML to, will omit if none specified",
+	)
+
+	rootCmd.AddCommand(reportCmd)
+}
+
+func runReport(rootOpts *rootOptions) (err error) {
+	if reportUser == "" {
+		return fmt.Errorf("--user is required")
+	}
+
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { finish(err) }()
+
+	// Everyone's activity is needed to compare the user with
+	opts := *rootOpts
+	opts.users = nil
+
+	d, err := dataFromGitHub(ctx, &opts)
+	md := metadata(ctx, err)
+	if err != nil && (md == nil || !md.Incomplete) {
+		return err
+	}
+
+	options := report.Options{
+		User:     reportUser,
+		Title:    rootOpts.title,
+		Since:    rootOpts.sinceParsed,
+		Until:    rootOpts.untilParsed,
+		Warnings: warnings(md),
+	}
+
+	if reportHTMLOutput != "" {
+		out, err := report.HTML(options, d.PRs, d.Reviews, d.Issues, d.Comments)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(reportHTMLOutput, []byte(out), 0o644); err != nil {
+			return err
+		}
+	}
+
+	out, err := report.Markdown(options, d.PRs, d.Reviews, d.Issues, d.Comments)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+
+	return incompleteError(md, rootOpts)
+}
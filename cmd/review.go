@@ -15,13 +15,9 @@
 package cmd
 
 import (
-	"context"
-
-	"github.com/google/pullsheet/pkg/summary"
 	"github.com/spf13/cobra"
 
 	"github.com/google/pullsheet/pkg/client"
-	"github.com/google/pullsheet/pkg/print"
 )
 
 // reviewsCmd represents the subcommand for `pullsheet reviews`
@@ -39,22 +35,22 @@ func init() {
 	rootCmd.AddCommand(reviewsCmd)
 }
 
-func runReviews(rootOpts *rootOptions) error {
-	ctx := context.Background()
-	c, err := client.New(ctx, client.Config{GitHubTokenPath: rootOpts.tokenPath})
-	if err != nil {
-		return err
-	}
+func runReviews(rootOpts *rootOptions) (err error) {
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
 
-	data, err := summary.Reviews(ctx, c, rootOpts.repos, rootOpts.users, rootOpts.sinceParsed, rootOpts.untilParsed)
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
 	if err != nil {
 		return err
 	}
+	defer func() { finish(err) }()
+	ctx = streamContext(ctx, rootOpts)
 
-	err = print.Print(data, rootOpts.out)
+	c, err := client.New(ctx, clientConfig(rootOpts))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	data, err := reviews(ctx, c, rootOpts, rootOpts.repos)
+	return printResults(ctx, data, err, rootOpts)
 }
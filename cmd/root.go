@@ -17,6 +17,9 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/karrick/tparse"
@@ -24,6 +27,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/ghcache"
+	"github.com/google/pullsheet/pkg/period"
+	"github.com/google/pullsheet/pkg/print"
 )
 
 const dateForm = "2006-01-02"
@@ -38,18 +46,49 @@ pullsheet generates a CSV (comma separated values) & HTML output about GitHub ac
 }
 
 type rootOptions struct {
-	org         string
-	repos       []string
-	users       []string
-	since       string
-	until       string
-	sinceParsed time.Time
-	untilParsed time.Time
-	title       string
-	tokenPath   string
-	branches    []string
-	out         string
-	includeBots bool // if true will include bots in the metrics
+	org            string
+	repos          []string
+	users          []string
+	since          string
+	until          string
+	sinceParsed    time.Time
+	untilParsed    time.Time
+	title          string
+	tokenPath      string
+	branches       []string
+	out            string
+	includeBots    bool                     // if true will include bots in the metrics
+	strategy       string                   // how to locate merged pull requests
+	backend        string                   // API used to collect pull request details
+	stateFile      string                   // if set, results are updated incrementally in this file
+	resume         string                   // ID of an interrupted run to resume
+	checkpointDir  string                   // where run checkpoints are stored
+	cacheTTL       map[string]string        // cache TTL overrides by object type
+	httpCache      bool                     // if true, responses are stored for conditional requests
+	httpCacheDir   string                   // directory to store responses for conditional requests in
+	cacheTTLParsed map[string]time.Duration // effective cache TTL by object type
+	record         string                   // directory to record GitHub responses in
+	replay         string                   // directory to replay GitHub responses from
+	timeout        time.Duration            // how long a run may take before stopping with partial results
+	keepGoing      bool                     // if true, failed repositories are reported rather than ending the run
+	failOnErrors   bool                     // if true, exit non-zero when --keep-going skipped repositories
+	progress       bool                     // if true, progress is reported on stderr
+	config         string                   // config file holding report profiles
+	profile        string                   // name of the profile to take defaults from
+	period         string                   // calendar period to report on, in place of since and until
+	timezone       string                   // time zone in which dates and periods are interpreted
+	fyStart        string                   // month in which fiscal years start
+	location       *time.Location           // parsed timezone
+	db             string                   // SQLite database to write results to, with --out SQLite
+	parquetDir     string                   // directory to write partitioned Parquet files to, with --out Parquet
+	columns        []string                 // CSV columns to include, in order
+	excludeColumns []string                 // CSV columns to leave out
+	sortBy         []string                 // CSV columns to sort rows by
+	flatten        bool                     // if true, multi-line CSV fields are joined onto one line
+	delimiter      string                   // CSV delimiter
+	bom            bool                     // if true, CSV output starts with a UTF-8 byte order mark
+	csvComments    bool                     // if true, CSV output is preceded by metadata comment lines
+	csv            print.CSVOptions         // parsed CSV options
 }
 
 var rootOpts = &rootOptions{}
@@ -58,6 +97,9 @@ var rootOpts = &rootOptions{}
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if errors.Is(err, errIncomplete) {
+			klog.Exit(err)
+		}
 		klog.Fatal(err)
 	}
 }
@@ -108,6 +150,27 @@ func init() {
 		"when to query till (date or duration)",
 	)
 
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.period,
+		"period",
+		"",
+		"Calendar period to query, in place of --since and --until: such as 2024-Q3, 2024-03, 2024-W12, fy2025, last-month or this-quarter",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.timezone,
+		"timezone",
+		"UTC",
+		"Time zone in which dates and periods are interpreted, such as America/New_York or Local",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.fyStart,
+		"fiscal-year-start",
+		"january",
+		"Month in which fiscal years start. Fiscal years are named after the calendar year in which they end",
+	)
+
 	rootCmd.PersistentFlags().BoolVarP(
 		&rootOpts.includeBots,
 		"include-bots",
@@ -134,7 +197,175 @@ func init() {
 		&rootOpts.out,
 		"out",
 		"CSV",
-		"Output type - CSV/JSON. Default is CSV",
+		"Output type - CSV/JSON/NDJSON/Markdown/XLSX/Parquet/SQLite. Default is CSV",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.db,
+		"db",
+		"",
+		"SQLite database to create or update with the results, if --out is SQLite",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.parquetDir,
+		"parquet-dir",
+		"",
+		"Directory to write Parquet files to, partitioned by project and month, if --out is Parquet. Defaults to standard output",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootOpts.columns,
+		"columns",
+		[]string{},
+		"CSV columns to include, in this order. Defaults to all",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootOpts.excludeColumns,
+		"exclude-columns",
+		[]string{},
+		"CSV columns to leave out",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootOpts.sortBy,
+		"sort",
+		[]string{},
+		"CSV columns to sort rows by, descending if prefixed with -, such as -Delta,Date",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.flatten,
+		"flatten",
+		false,
+		"Join the lines of multi-line CSV fields, such as Description and Files, with \" | \"",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.delimiter,
+		"delimiter",
+		"comma",
+		"CSV delimiter - comma/tab/semicolon, or a single character",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.bom,
+		"bom",
+		false,
+		"Start CSV output with a UTF-8 byte order mark, so that Excel reads it as UTF-8",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.csvComments,
+		"csv-comments",
+		false,
+		"Precede CSV output with '#' lines for repositories that failed, rather than logging them. Incomplete CSV output always starts with a '# incomplete' line",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.strategy,
+		"strategy",
+		client.StrategyList,
+		"How to locate merged PRs - list/search. search is cheaper for small, old windows on busy repos",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.backend,
+		"backend",
+		client.BackendREST,
+		"API used to collect PR details - rest/graphql. graphql fetches PRs with their files and comments in bulk, falling back to rest on failure",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.stateFile,
+		"incremental-state",
+		"",
+		"JSON file holding results of previous runs. If set, only items updated since the last run are fetched and merged into it",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.resume,
+		"resume",
+		"",
+		"ID of an interrupted run to continue, skipping repositories it completed",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.checkpointDir,
+		"checkpoint-dir",
+		"",
+		"Directory to store run checkpoints in. Defaults to the user cache directory",
+	)
+
+	rootCmd.PersistentFlags().StringToStringVar(
+		&rootOpts.cacheTTL,
+		"cache-ttl",
+		map[string]string{},
+		"How long to use cached objects before revalidating them, by type. ex: pr=24h,issue-comments=6h",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.httpCache,
+		"http-cache",
+		false,
+		"Store GitHub responses on disk and revalidate them with conditional requests, which cost no rate limit",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.record,
+		"record",
+		"",
+		"Directory to record GitHub requests and responses in, without credentials, for use with --replay",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.replay,
+		"replay",
+		"",
+		"Directory of responses recorded with --record to answer GitHub requests from, offline and without a token. Requests only match with the same absolute --since and --until dates as were recorded",
+	)
+
+	rootCmd.PersistentFlags().DurationVar(
+		&rootOpts.timeout,
+		"timeout",
+		0,
+		"How long a run may take. Once exceeded, or on Ctrl-C, the results collected so far are written marked as incomplete",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.keepGoing,
+		"keep-going",
+		false,
+		"Continue with the remaining repositories when one fails, reporting the failures in the output",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.failOnErrors,
+		"fail-on-repo-errors",
+		false,
+		"With --keep-going, exit non-zero if any repository failed",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.progress,
+		"progress",
+		true,
+		"Report progress on stderr: a live line on a terminal, otherwise a log line every 30s",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.config,
+		"config",
+		"",
+		"Config file holding report profiles. Defaults to pullsheet.yaml in the current directory or user config directory",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.profile,
+		"profile",
+		"",
+		"Name of a profile in the config file to take settings from. Flags override profile settings",
 	)
 
 	// Set up viper flag handling
@@ -149,7 +380,7 @@ func initRootOpts() error {
 	// Set up viper environment variable handling
 	viper.SetEnvPrefix("pullsheet")
 	envKeys := []string{
-		"repos", "branches", "users", "since", "until", "title", "token-path", "out",
+		"org", "repos", "branches", "users", "since", "until", "title", "token-path", "out", "strategy", "backend", "incremental-state", "checkpoint-dir", "timeout", "keep-going", "progress", "config", "profile", "period", "timezone", "fiscal-year-start", "db", "parquet-dir", "columns", "exclude-columns", "sort", "flatten", "delimiter", "bom", "csv-comments", "http-cache",
 	}
 	for _, key := range envKeys {
 		if err := viper.BindEnv(key); err != nil {
@@ -157,11 +388,13 @@ func initRootOpts() error {
 		}
 	}
 
-	if rootOpts.out != "JSON" && rootOpts.out != "CSV" {
-		return fmt.Errorf("invalid out parameter %s. Must be JSON or CSV", rootOpts.out)
+	// Profile settings take precedence over flag defaults, but not over flags or env variables
+	if err := applyProfile(viper.GetString("config"), viper.GetString("profile")); err != nil {
+		return err
 	}
 
 	// Set options. viper will prioritize flags over env variables
+	rootOpts.org = viper.GetString("org")
 	rootOpts.repos = viper.GetStringSlice("repos")
 	rootOpts.branches = viper.GetStringSlice("branches")
 	rootOpts.users = viper.GetStringSlice("users")
@@ -171,40 +404,184 @@ func initRootOpts() error {
 	rootOpts.tokenPath = viper.GetString("token-path")
 	rootOpts.out = viper.GetString("out")
 	rootOpts.includeBots = viper.GetBool("include-bots")
+	rootOpts.strategy = viper.GetString("strategy")
+	rootOpts.backend = viper.GetString("backend")
+	rootOpts.stateFile = viper.GetString("incremental-state")
+	rootOpts.resume = viper.GetString("resume")
+	rootOpts.checkpointDir = viper.GetString("checkpoint-dir")
+	rootOpts.record = viper.GetString("record")
+	rootOpts.replay = viper.GetString("replay")
+	rootOpts.timeout = viper.GetDuration("timeout")
+	rootOpts.keepGoing = viper.GetBool("keep-going")
+	rootOpts.failOnErrors = viper.GetBool("fail-on-repo-errors")
+	rootOpts.progress = viper.GetBool("progress")
+	rootOpts.period = viper.GetString("period")
+	rootOpts.timezone = viper.GetString("timezone")
+	rootOpts.fyStart = viper.GetString("fiscal-year-start")
+	rootOpts.db = viper.GetString("db")
+	rootOpts.parquetDir = viper.GetString("parquet-dir")
+	rootOpts.columns = viper.GetStringSlice("columns")
+	rootOpts.excludeColumns = viper.GetStringSlice("exclude-columns")
+	rootOpts.sortBy = viper.GetStringSlice("sort")
+	rootOpts.flatten = viper.GetBool("flatten")
+	rootOpts.delimiter = viper.GetString("delimiter")
+	rootOpts.bom = viper.GetBool("bom")
+	rootOpts.csvComments = viper.GetBool("csv-comments")
+	rootOpts.httpCache = viper.GetBool("http-cache")
+
+	out, ok := outputFormat(rootOpts.out)
+	if !ok {
+		return fmt.Errorf("invalid out parameter %s. Must be one of %s", rootOpts.out, strings.Join(outputFormats(), ", "))
+	}
+	rootOpts.out = out
+	if rootOpts.strategy == client.StrategySearch && rootOpts.backend == client.BackendGraphQL {
+		return fmt.Errorf("--strategy %s cannot be combined with --backend %s", client.StrategySearch, client.BackendGraphQL)
+	}
+	if rootOpts.out == "SQLite" && rootOpts.db == "" {
+		return fmt.Errorf("--out SQLite requires --db")
+	}
+
+	delimiter, err := print.ParseDelimiter(rootOpts.delimiter)
+	if err != nil {
+		return err
+	}
+	rootOpts.csv = print.CSVOptions{
+		Columns:        rootOpts.columns,
+		ExcludeColumns: rootOpts.excludeColumns,
+		Sort:           rootOpts.sortBy,
+		Flatten:        rootOpts.flatten,
+		Delimiter:      delimiter,
+		BOM:            rootOpts.bom,
+		Comments:       rootOpts.csvComments,
+	}
+	if rootOpts.out != "CSV" && (len(rootOpts.columns) > 0 || len(rootOpts.excludeColumns) > 0 || len(rootOpts.sortBy) > 0 || rootOpts.flatten || delimiter != ',' || rootOpts.bom || rootOpts.csvComments) {
+		return fmt.Errorf("--columns, --exclude-columns, --sort, --flatten, --delimiter, --bom and --csv-comments only apply to --out CSV")
+	}
+
+	rootOpts.cacheTTLParsed = map[string]time.Duration{}
+	for typ, d := range ghcache.DefaultTTL {
+		rootOpts.cacheTTLParsed[typ] = d
+	}
+	for typ, s := range rootOpts.cacheTTL {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid cache-ttl for %s: %v", typ, err)
+		}
+		rootOpts.cacheTTLParsed[typ] = d
+	}
+
+	if rootOpts.httpCache {
+		rootOpts.httpCacheDir, err = ghcache.HTTPDir()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func initCommand(*cobra.Command, []string) error {
+// clientConfig returns the GitHub client configuration for the root options
+func clientConfig(rootOpts *rootOptions) client.Config {
+	return client.Config{
+		GitHubTokenPath: rootOpts.tokenPath,
+		PullsStrategy:   rootOpts.strategy,
+		Backend:         rootOpts.backend,
+		CacheTTL:        rootOpts.cacheTTLParsed,
+		HTTPCachePath:   rootOpts.httpCacheDir,
+		RecordPath:      rootOpts.record,
+		ReplayPath:      rootOpts.replay,
+		IncludeBots:     rootOpts.includeBots,
+		Location:        rootOpts.location,
+	}
+}
+
+// commandLine returns the command line pullsheet was run with
+func commandLine() string {
+	return filepath.Base(os.Args[0]) + " " + strings.Join(os.Args[1:], " ")
+}
+
+func initCommand(cmd *cobra.Command, _ []string) error {
 	if err := initRootOpts(); err != nil {
 		return err
 	}
 
 	var err error
+	rootOpts.location, err = time.LoadLocation(rootOpts.timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	now := time.Now().In(rootOpts.location)
+
+	// A window given on the command line overrides a period from a profile or environment variable
+	window := cmd.Flags().Changed("since") || cmd.Flags().Changed("until")
+	if window && cmd.Flags().Changed("period") {
+		return fmt.Errorf("--period cannot be combined with --since or --until")
+	}
+	if window && rootOpts.period != "" {
+		klog.Infof("--since and --until override period %q", rootOpts.period)
+		rootOpts.period = ""
+	}
 
-	t, err := tparse.ParseNow(dateForm, rootOpts.since)
-	if err == nil {
-		rootOpts.sinceParsed = t
-	} else {
-		klog.Infof("%q not a duration: %v", rootOpts.since, err)
-		rootOpts.sinceParsed, err = time.Parse(dateForm, rootOpts.since)
+	if rootOpts.period != "" {
+		fyStart, err := period.ParseMonth(rootOpts.fyStart)
 		if err != nil {
-			return errors.Wrap(err, "since time parse")
+			return fmt.Errorf("invalid fiscal-year-start: %v", err)
 		}
-	}
 
-	rootOpts.untilParsed = time.Now()
-	if rootOpts.since != "" {
-		t, err := tparse.ParseNow(dateForm, rootOpts.until)
-		if err == nil {
-			rootOpts.untilParsed = t
-		} else {
-			klog.Infof("%q not a duration: %v", rootOpts.until, err)
-			rootOpts.untilParsed, err = time.Parse(dateForm, rootOpts.until)
-			if err != nil {
-				return errors.Wrap(err, "until time parse")
-			}
+		p, err := period.Parse(rootOpts.period, now, fyStart)
+		if err != nil {
+			return err
 		}
+
+		// until is inclusive
+		rootOpts.sinceParsed = p.Since
+		rootOpts.untilParsed = p.Until.Add(-time.Nanosecond)
+		klog.Infof("period %s is %s to %s", p.Name, rootOpts.sinceParsed, rootOpts.untilParsed)
+		return nil
+	}
+
+	rootOpts.sinceParsed, err = parseTime(rootOpts.since, now)
+	if err != nil {
+		return errors.Wrap(err, "since time parse")
+	}
+
+	rootOpts.untilParsed, err = parseTime(rootOpts.until, now)
+	if err != nil {
+		return errors.Wrap(err, "until time parse")
 	}
 
 	return nil
 }
+
+// parseTime parses a date, in the time zone of now, or a duration relative to now such as now-90d. An empty string
+// is now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return now, nil
+	}
+
+	if t, err := time.ParseInLocation(dateForm, s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	t, err := tparse.ParseWithMap(dateForm, s, map[string]time.Time{"now": now})
+	if err != nil {
+		klog.Infof("%q not a date or duration: %v", s, err)
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// outputFormats returns the output types: those print supports, and SQLite
+func outputFormats() []string {
+	return append(append([]string{}, print.Formats...), "SQLite")
+}
+
+// outputFormat returns the output type named by out, ignoring case, and whether there is one
+func outputFormat(out string) (string, bool) {
+	for _, f := range outputFormats() {
+		if strings.EqualFold(out, f) {
+			return f, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// schemaCmd represents the subcommand for `pullsheet schema`
+var schemaCmd = &cobra.Command{
+	Use:           "schema [prs|reviews|issues|comments]",
+	Short:         "Print the JSON Schema of the records of JSON and NDJSON output",
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSchema(args)
+	},
+}
+
+// schemaRecords are the records of each summary type, by the name of the type
+var schemaRecords = map[string]interface{}{
+	"prs":      repo.PRSummary{},
+	"reviews":  repo.ReviewSummary{},
+	"issues":   repo.IssueSummary{},
+	"comments": repo.CommentSummary{},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+// runSchema prints the schema of one summary type, or an object holding the schema of each type by its name
+func runSchema(args []string) error {
+	names := []string{}
+	for name := range schemaRecords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schemas := map[string]interface{}{}
+	for _, name := range names {
+		s, err := print.JSONSchema(schemaRecords[name], strings.TrimPrefix(fmt.Sprintf("%T", schemaRecords[name]), "repo."))
+		if err != nil {
+			return err
+		}
+		schemas[name] = s
+	}
+
+	var v interface{} = schemas
+	if len(args) > 0 {
+		s, ok := schemas[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown summary type %q, must be one of %s", args[0], strings.Join(names, ", "))
+		}
+		v = s
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
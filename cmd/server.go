@@ -57,7 +57,7 @@ func init() {
 
 func runServer(rootOpts *rootOptions) error {
 	ctx := context.Background()
-	c, err := client.New(ctx, client.Config{GitHubTokenPath: rootOpts.tokenPath})
+	c, err := client.New(ctx, clientConfig(rootOpts))
 	if err != nil {
 		return err
 	}
@@ -72,6 +72,7 @@ func runServer(rootOpts *rootOptions) error {
 			Until:          rootOpts.untilParsed,
 			Title:          rootOpts.title,
 			DisableCaching: disableCaching,
+			Command:        commandLine(),
 		})
 
 	s := server.New(ctx, c, j)
@@ -0,0 +1,72 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/google/pullsheet/pkg/timeseries"
+)
+
+var (
+	// timeseriesCmd represents the subcommand for `pullsheet timeseries`
+	timeseriesCmd = &cobra.Command{
+		Use:           "timeseries",
+		Short:         "Generate activity per user by day, week or month",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTimeseries(rootOpts)
+		},
+	}
+
+	bucketUnit string
+)
+
+func init() {
+	timeseriesCmd.Flags().StringVar(
+		&bucketUnit,
+		"bucket",
+		"week",
+		"Length of each bucket of activity: day, week or month",
+	)
+
+	rootCmd.AddCommand(timeseriesCmd)
+}
+
+func runTimeseries(rootOpts *rootOptions) (err error) {
+	if rootOpts.out == "SQLite" || rootOpts.out == "Parquet" {
+		return fmt.Errorf("timeseries cannot be written to %s, write the summaries with leaderboard --out %s instead", rootOpts.out, rootOpts.out)
+	}
+
+	ctx, cancel := runContext(rootOpts)
+	defer cancel()
+
+	ctx, finish, err := checkpointContext(ctx, rootOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { finish(err) }()
+
+	d, err := dataFromGitHub(ctx, rootOpts)
+	buckets, serr := timeseries.Split(bucketUnit, rootOpts.location, d.PRs, d.Reviews, d.Issues, d.Comments)
+	if serr != nil {
+		return serr
+	}
+
+	return printResults(ctx, timeseries.Points(buckets), err, rootOpts)
+}
@@ -17,27 +17,56 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v33/github"
 	"golang.org/x/oauth2"
+	"k8s.io/klog/v2"
 
+	"github.com/google/pullsheet/pkg/ghcache"
 	"github.com/google/triage-party/pkg/persist"
 )
 
+const (
+	// StrategyList locates merged pull requests by paging through closed pull requests
+	StrategyList = "list"
+	// StrategySearch locates merged pull requests using the GitHub Search API
+	StrategySearch = "search"
+
+	// BackendREST collects pull request details using the GitHub REST v3 API
+	BackendREST = "rest"
+	// BackendGraphQL collects pull request details in bulk using the GitHub GraphQL v4 API
+	BackendGraphQL = "graphql"
+)
+
 // Client is a client for interacting with GitHub and a cache.
 type Client struct {
-	Cache        persist.Cacher
-	GitHubClient *github.Client
+	Cache         persist.Cacher
+	GitHubClient  *github.Client
+	HTTPClient    *http.Client   // Authenticated client, used for GraphQL queries.
+	PullsStrategy string         // How to locate merged pull requests: StrategyList or StrategySearch.
+	Backend       string         // API used to collect pull request details: BackendREST or BackendGraphQL.
+	IncludeBots   bool           // Whether activity by bots is included.
+	Location      *time.Location // Time zone of dates and timestamps in summaries. Defaults to UTC.
 }
 
 // Config is the configuration for a Client.
 type Config struct {
 	GitHubTokenPath string
 	GitHubToken     string
-	PersistBackend  string // Backend to persist data.
-	PersistPath     string // Path to persist data.
+	PersistBackend  string                   // Backend to persist data.
+	PersistPath     string                   // Path to persist data.
+	PullsStrategy   string                   // How to locate merged pull requests. Defaults to StrategyList.
+	Backend         string                   // API used to collect pull request details. Defaults to BackendREST.
+	CacheTTL        map[string]time.Duration // How long cached objects are used, by type. Defaults to ghcache.DefaultTTL.
+	HTTPCachePath   string                   // If set, responses are stored in this directory for conditional requests.
+	RecordPath      string                   // If set, every request and response is recorded in this directory.
+	ReplayPath      string                   // If set, responses are replayed from this directory instead of GitHub.
+	IncludeBots     bool                     // Whether activity by bots is included.
+	Location        *time.Location           // Time zone of dates and timestamps in summaries. Defaults to UTC.
 }
 
 // New creates a new github Client.
@@ -50,11 +79,40 @@ func New(ctx context.Context, c Config) (*Client, error) {
 		c.PersistPath = os.Getenv("PERSIST_PATH")
 	}
 
+	switch c.PullsStrategy {
+	case "":
+		c.PullsStrategy = StrategyList
+	case StrategyList, StrategySearch:
+	default:
+		return nil, fmt.Errorf("unknown pulls strategy %q. Must be %s or %s", c.PullsStrategy, StrategyList, StrategySearch)
+	}
+
+	switch c.Backend {
+	case "":
+		c.Backend = BackendREST
+	case BackendREST, BackendGraphQL:
+	default:
+		return nil, fmt.Errorf("unknown backend %q. Must be %s or %s", c.Backend, BackendREST, BackendGraphQL)
+	}
+
+	if c.PullsStrategy == StrategySearch && c.Backend == BackendGraphQL {
+		return nil, fmt.Errorf("the %s strategy cannot be combined with the %s backend", StrategySearch, BackendGraphQL)
+	}
+
+	if c.RecordPath != "" && c.ReplayPath != "" {
+		return nil, fmt.Errorf("cannot record and replay at the same time")
+	}
+
+	// Recording and replaying bypass the caches, so that a recording holds every request a run makes.
+	if c.RecordPath != "" || c.ReplayPath != "" {
+		c.PersistBackend = "memory"
+	}
+
 	if c.GitHubToken == "" {
 		c.GitHubToken = strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
 	}
 
-	if c.GitHubToken == "" {
+	if c.GitHubToken == "" && c.ReplayPath == "" {
 		bs, err := os.ReadFile(c.GitHubTokenPath)
 		if err != nil {
 			return nil, err
@@ -62,7 +120,16 @@ func New(ctx context.Context, c Config) (*Client, error) {
 		c.GitHubToken = strings.TrimSpace(string(bs))
 	}
 
-	tc := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.GitHubToken}))
+	if c.CacheTTL == nil {
+		c.CacheTTL = ghcache.DefaultTTL
+	}
+
+	rt, err := transport(c)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &http.Client{Transport: rt}
 	gc := github.NewClient(tc)
 
 	p, err := persist.FromEnv("pullsheet", c.PersistBackend, c.PersistPath)
@@ -75,7 +142,41 @@ func New(ctx context.Context, c Config) (*Client, error) {
 	}
 
 	return &Client{
-		Cache:        p,
-		GitHubClient: gc,
+		Cache:         ghcache.WithTTL(p, c.CacheTTL),
+		GitHubClient:  gc,
+		HTTPClient:    tc,
+		PullsStrategy: c.PullsStrategy,
+		Backend:       c.Backend,
+		IncludeBots:   c.IncludeBots,
+		Location:      c.Location,
 	}, nil
 }
+
+// transport returns the HTTP transport for GitHub requests
+func transport(c Config) (http.RoundTripper, error) {
+	if c.ReplayPath != "" {
+		klog.Infof("replaying GitHub responses from %s", c.ReplayPath)
+		return newReplayTransport(c.ReplayPath)
+	}
+
+	var rt http.RoundTripper = &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.GitHubToken}),
+		Base:   http.DefaultTransport,
+	}
+
+	if c.RecordPath != "" {
+		klog.Infof("recording GitHub responses to %s", c.RecordPath)
+		return newRecordTransport(rt, c.RecordPath)
+	}
+
+	// Stored responses are a cache too, so they are not kept when the memory backend is in use
+	if c.HTTPCachePath == "" || c.PersistBackend == "memory" {
+		return rt, nil
+	}
+
+	rt, err := ghcache.NewTransport(rt, c.HTTPCachePath, c.CacheTTL["http"])
+	if err != nil {
+		return nil, fmt.Errorf("http cache: %v", err)
+	}
+	return rt, nil
+}
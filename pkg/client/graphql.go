@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GraphQLURL is the endpoint for the GitHub GraphQL v4 API
+var GraphQLURL = "https://api.github.com/graphql"
+
+// GraphQL runs a query against the GitHub GraphQL API, decoding the response data into out.
+func (c *Client) GraphQL(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return fmt.Errorf("marshal: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, GraphQLURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("graphql: %s: %s", resp.Status, bytes.TrimSpace(bs))
+	}
+
+	var r struct {
+		Data   json.RawMessage
+		Errors []struct {
+			Message string
+		}
+	}
+	if err := json.Unmarshal(bs, &r); err != nil {
+		return fmt.Errorf("unmarshal: %v", err)
+	}
+
+	if len(r.Errors) > 0 {
+		msgs := []string{}
+		for _, e := range r.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("graphql: %s", strings.Join(msgs, "; "))
+	}
+
+	return json.Unmarshal(r.Data, out)
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// sensitiveHeaders are removed from recordings, so that they can be shared
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Oauth-Scopes",
+	"X-Oauth-Client-Id",
+	"X-Accepted-Oauth-Scopes",
+	"X-Github-Sso",
+}
+
+// recording is a request and its response, as stored on disk
+type recording struct {
+	Method        string
+	URL           string
+	RequestHeader http.Header
+	RequestBody   string `json:",omitempty"`
+	StatusCode    int
+	Status        string
+	Header        http.Header
+	Body          string
+}
+
+// recordTransport is an http.RoundTripper which stores every request and response in a directory
+type recordTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+// replayTransport is an http.RoundTripper which answers requests from a directory written by recordTransport
+type replayTransport struct {
+	dir string
+}
+
+// newRecordTransport returns a transport recording the requests sent through base into dir
+func newRecordTransport(base http.RoundTripper, dir string) (*recordTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	return &recordTransport{base: base, dir: dir}, nil
+}
+
+// newReplayTransport returns a transport replaying the requests recorded in dir
+func newReplayTransport(dir string) (*replayTransport, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	return &replayTransport{dir: dir}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r := &recording{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestHeader: scrub(req.Header),
+		RequestBody:   string(reqBody),
+		StatusCode:    resp.StatusCode,
+		Status:        resp.Status,
+		Header:        scrub(resp.Header),
+		Body:          string(body),
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := recordingPath(t.dir, req, reqBody)
+	klog.V(1).Infof("recording %s %s to %s", req.Method, req.URL, path)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+
+	return resp, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path := recordingPath(t.dir, req, reqBody)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Search queries hold the window of the run, which relative dates move
+		if strings.HasPrefix(req.URL.Path, "/search/") {
+			return nil, fmt.Errorf("replay: no recording of %s %s in %s (searches only match when replayed with the same absolute dates as were recorded)", req.Method, req.URL, t.dir)
+		}
+		return nil, fmt.Errorf("replay: no recording of %s %s in %s", req.Method, req.URL, t.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+
+	var r recording
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("replay: %s: %w", path, err)
+	}
+
+	klog.V(1).Infof("replaying %s %s from %s", req.Method, req.URL, path)
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the body of a request, returning a copy of the request which can still be sent
+func readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return req, b, nil
+}
+
+// recordingPath returns where the recording of a request is stored. Requests are identified by their
+// method, URL, accepted media type and body, as the same URL may be requested with different media types.
+func recordingPath(dir string, req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", req.Method, req.URL.String(), req.Header.Get("Accept"))
+	h.Write(body)
+	return filepath.Join(dir, fmt.Sprintf("%s-%x.json", strings.ToLower(req.Method), h.Sum(nil)[:16]))
+}
+
+// scrub returns a copy of h without credentials
+func scrub(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range sensitiveHeaders {
+		h.Del(k)
+	}
+	return h
+}
@@ -0,0 +1,323 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/triage-party/pkg/persist"
+)
+
+// keyTypes are the types of object stored in the cache, as key prefixes. "sync" entries are the watermarks of
+// incremental runs. "http" entries are responses stored for conditional requests, which are kept in a directory of
+// their own.
+var keyTypes = []string{"pr-listfiles", "pr-comments", "pr", "gql-pr", "issue-comments", "issue", "sync", "http"}
+
+// Entry describes an object stored in a disk cache
+type Entry struct {
+	Dir     string // Directory the entry is stored in
+	Key     string
+	Type    string
+	Repo    string // org/project the object belongs to, if it names one
+	Size    int64
+	Created time.Time
+	Err     error // Set if the entry could not be decoded
+}
+
+// InRepo returns whether the entry refers to an object within a repository. Keys do not tell org-a/b from org/a-b,
+// so the repository the object names is used where there is one.
+func (e Entry) InRepo(org string, project string) bool {
+	if e.Repo != "" {
+		return strings.EqualFold(e.Repo, org+"/"+project)
+	}
+	return KeyInRepo(e.Key, org, project)
+}
+
+// DiskDir returns the directory used by the disk persist backend for a configured path
+func DiskDir(backend string, path string) (string, error) {
+	if backend == "" {
+		backend = os.Getenv("PERSIST_BACKEND")
+	}
+	if backend != "" && backend != "disk" {
+		return "", fmt.Errorf("cache management is only supported for the disk backend, not %q", backend)
+	}
+
+	if path == "" {
+		path = os.Getenv("PERSIST_PATH")
+	}
+	if path != "" {
+		return path, nil
+	}
+
+	root, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+	return filepath.Join(root, "pullsheet"), nil
+}
+
+// KeyType returns the type of object a cache key refers to, such as "pr-listfiles"
+func KeyType(key string) string {
+	for _, typ := range keyTypes {
+		if strings.HasPrefix(key, typ+"-") {
+			return typ
+		}
+	}
+	return strings.SplitN(key, "-", 2)[0]
+}
+
+// KeyInRepo returns whether a cache key, such as pr-<org>-<project>-<number>, refers to an object within a repository
+func KeyInRepo(key string, org string, project string) bool {
+	prefix := KeyType(key) + "-" + org + "-" + project + "-"
+	if len(key) <= len(prefix) || !strings.EqualFold(key[:len(prefix)], prefix) {
+		return false
+	}
+	_, err := strconv.Atoi(key[len(prefix):])
+	return err == nil
+}
+
+// DiskEntries returns the entries of a disk cache, sorted by key
+func DiskEntries(dir string) ([]Entry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	es := []Entry{}
+	for _, de := range des {
+		if de.IsDir() || strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+
+		e := Entry{Dir: dir, Key: de.Name(), Type: KeyType(de.Name())}
+		b, err := os.ReadFile(filepath.Join(dir, de.Name()))
+		if err != nil {
+			return nil, err
+		}
+		e.Size = int64(len(b))
+
+		var bl persist.Blob
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&bl); err != nil {
+			e.Err = err
+		}
+		e.Created = bl.Created
+		e.Repo = blobRepo(&bl)
+
+		es = append(es, e)
+	}
+
+	sort.Slice(es, func(i, j int) bool { return es[i].Key < es[j].Key })
+	return es, nil
+}
+
+// HTTPEntries returns the responses stored for conditional requests in dir, sorted by key. They are as old as their
+// last revalidation. A missing directory has no entries.
+func HTTPEntries(dir string) ([]Entry, error) {
+	des, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	es := []Entry{}
+	for _, de := range des {
+		if de.IsDir() || !strings.HasPrefix(de.Name(), "http-") {
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+		e := Entry{Dir: dir, Key: de.Name(), Type: "http", Size: fi.Size(), Created: fi.ModTime()}
+
+		b, err := os.ReadFile(filepath.Join(dir, de.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var s storedResponse
+		if err := json.Unmarshal(b, &s); err != nil {
+			e.Err = err
+		}
+		e.Repo = apiRepo(s.URL)
+
+		es = append(es, e)
+	}
+
+	sort.Slice(es, func(i, j int) bool { return es[i].Key < es[j].Key })
+	return es, nil
+}
+
+// blobRepo returns the org/project of the object in a cached blob, or "" if it does not name one
+func blobRepo(bl *persist.Blob) string {
+	u := ""
+	switch {
+	case bl.GHPullRequest != nil:
+		u = bl.GHPullRequest.GetHTMLURL()
+	case bl.GHIssue != nil:
+		u = bl.GHIssue.GetHTMLURL()
+	case len(bl.GHCommitFiles) > 0:
+		u = bl.GHCommitFiles[0].GetBlobURL()
+	case len(bl.GHPullRequestComments) > 0:
+		u = bl.GHPullRequestComments[0].GetHTMLURL()
+	case len(bl.GHIssueComments) > 0:
+		u = bl.GHIssueComments[0].GetHTMLURL()
+	}
+
+	p, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(p.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// apiRepo returns the org/project of a GitHub API URL, such as https://api.github.com/repos/org/project/pulls, or ""
+// if it does not name one
+func apiRepo(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(p.Path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "repos" {
+			return parts[i+1] + "/" + parts[i+2]
+		}
+	}
+	return ""
+}
+
+// DiskRemove removes an entry from the disk cache it is stored in
+func DiskRemove(e Entry) error {
+	return os.Remove(filepath.Join(e.Dir, e.Key))
+}
+
+// DiskExport writes the given entries of disk caches to w as a gzipped tarball
+func DiskExport(es []Entry, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	for _, e := range es {
+		b, err := os.ReadFile(filepath.Join(e.Dir, e.Key))
+		if err != nil {
+			return err
+		}
+
+		if err := tw.WriteHeader(&tar.Header{Name: e.Key, Mode: 0o644, Size: int64(len(b)), ModTime: e.Created}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DiskImport reads a tarball written by DiskExport into a disk cache, and its stored responses into httpDir, returning
+// the number of entries imported. Entries which already exist are only replaced by newer ones.
+func DiskImport(dir string, httpDir string, r io.Reader) (int, error) {
+	for _, d := range []string{dir, httpDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return 0, fmt.Errorf("mkdir: %w", err)
+		}
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	tr := tar.NewReader(zr)
+
+	existing := map[string]time.Time{}
+	es, err := DiskEntries(dir)
+	if err != nil {
+		return 0, err
+	}
+	hes, err := HTTPEntries(httpDir)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range append(es, hes...) {
+		existing[e.Key] = e.Created
+	}
+
+	n := 0
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+
+		key := filepath.Base(h.Name)
+		if key != h.Name || strings.HasPrefix(key, ".") {
+			return n, fmt.Errorf("unexpected entry in cache export: %q", h.Name)
+		}
+
+		if t, ok := existing[key]; ok && !h.ModTime.After(t) {
+			continue
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return n, err
+		}
+
+		// Stored responses age from when they were last revalidated, which only their modification time records
+		if KeyType(key) == "http" {
+			path := filepath.Join(httpDir, key)
+			if err := os.WriteFile(path, b, 0o644); err != nil {
+				return n, err
+			}
+			if err := os.Chtimes(path, h.ModTime, h.ModTime); err != nil {
+				return n, err
+			}
+			n++
+			continue
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, key), b, 0o644); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
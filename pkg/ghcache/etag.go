@@ -0,0 +1,211 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Transport is an http.RoundTripper which makes GET requests conditional on the ETag of the last response stored
+// for the same URL. GitHub does not count "304 Not Modified" responses against the rate limit; the stored response
+// is returned in their place. This also covers list endpoints, such as PullRequests.List, which are not otherwise cached.
+type Transport struct {
+	Base http.RoundTripper
+	Dir  string        // Directory to store responses in
+	TTL  time.Duration // Stored responses older than this are discarded. Zero keeps them.
+}
+
+// storedResponse is a response with an ETag, as stored on disk
+type storedResponse struct {
+	URL    string
+	ETag   string
+	Header http.Header
+	Body   []byte
+}
+
+// HTTPDir returns the default directory to store responses for conditional requests in
+func HTTPDir() (string, error) {
+	root, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+	return filepath.Join(root, "pullsheet-http"), nil
+}
+
+// NewTransport returns a Transport storing responses in dir, removing those already older than ttl
+func NewTransport(base http.RoundTripper, dir string, ttl time.Duration) (*Transport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+
+	t := &Transport{Base: base, Dir: dir, TTL: ttl}
+	if err := t.expire(); err != nil {
+		return nil, fmt.Errorf("expire: %w", err)
+	}
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.Base.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	stored := t.load(path)
+	if stored != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", stored.ETag)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && stored != nil {
+		klog.V(1).Infof("not modified: %s", req.URL)
+		resp.Body.Close()
+		// A revalidated response is as good as a new one, so its age starts over
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			klog.Warningf("touching stored response for %s: %v", req.URL, err)
+		}
+		return stored.response(req, resp.Header), nil
+	}
+
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := t.store(path, &storedResponse{URL: req.URL.String(), ETag: resp.Header.Get("ETag"), Header: resp.Header, Body: body}); err != nil {
+			klog.Warningf("storing response for %s: %v", req.URL, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// response returns the stored response as a reply to req, carrying over the rate limit headers of the 304 response
+func (s *storedResponse) response(req *http.Request, notModified http.Header) *http.Response {
+	h := s.Header.Clone()
+	for k, v := range notModified {
+		if strings.HasPrefix(strings.ToLower(k), "x-ratelimit-") {
+			h[k] = v
+		}
+	}
+	h.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(s.Body)),
+		ContentLength: int64(len(s.Body)),
+		Request:       req,
+	}
+}
+
+func (t *Transport) path(req *http.Request) string {
+	h := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return filepath.Join(t.Dir, fmt.Sprintf("http-%x", h))
+}
+
+// expire removes the stored responses which are older than the TTL
+func (t *Transport) expire() error {
+	if t.TTL <= 0 {
+		return nil
+	}
+
+	des, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil || de.IsDir() || time.Since(fi.ModTime()) < t.TTL {
+			continue
+		}
+		if err := os.Remove(filepath.Join(t.Dir, de.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		n++
+	}
+
+	if n > 0 {
+		klog.Infof("removed %d stored responses older than %s from %s", n, t.TTL, t.Dir)
+	}
+	return nil
+}
+
+func (t *Transport) load(path string) *storedResponse {
+	if fi, err := os.Stat(path); err != nil || (t.TTL > 0 && time.Since(fi.ModTime()) >= t.TTL) {
+		return nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var s storedResponse
+	if err := json.Unmarshal(b, &s); err != nil {
+		klog.Warningf("ignoring unreadable stored response %s: %v", path, err)
+		return nil
+	}
+	return &s
+}
+
+func (t *Transport) store(path string, s *storedResponse) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(t.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
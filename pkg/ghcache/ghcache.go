@@ -26,7 +26,7 @@ import (
 
 // PullRequestsGet gets a pull request data from the cache or GitHub.
 func PullRequestsGet(ctx context.Context, p persist.Cacher, c *github.Client, t time.Time, org string, project string, num int) (*github.PullRequest, error) {
-	key := fmt.Sprintf("pr-%s-%s-%d", org, project, num)
+	key := pullRequestKey(org, project, num)
 	val := p.Get(key, t)
 
 	if val != nil {
@@ -48,7 +48,7 @@ func PullRequestsGet(ctx context.Context, p persist.Cacher, c *github.Client, t
 
 // PullRequestsListFiles gets a list of files in a pull request from the cache or GitHub.
 func PullRequestsListFiles(ctx context.Context, p persist.Cacher, c *github.Client, t time.Time, org string, project string, num int) ([]*github.CommitFile, error) {
-	key := fmt.Sprintf("pr-listfiles-%s-%s-%d", org, project, num)
+	key := pullRequestFilesKey(org, project, num)
 	val := p.Get(key, t)
 
 	if val != nil {
@@ -79,7 +79,7 @@ func PullRequestsListFiles(ctx context.Context, p persist.Cacher, c *github.Clie
 
 // PullRequestsListComments gets a list of comments in a pull request from the cache or GitHub for a given org, project, and number.
 func PullRequestsListComments(ctx context.Context, p persist.Cacher, c *github.Client, t time.Time, org string, project string, num int) ([]*github.PullRequestComment, error) {
-	key := fmt.Sprintf("pr-comments-%s-%s-%d", org, project, num)
+	key := pullRequestCommentsKey(org, project, num)
 	val := p.Get(key, t)
 
 	if val != nil {
@@ -112,7 +112,7 @@ func PullRequestsListComments(ctx context.Context, p persist.Cacher, c *github.C
 
 // IssuesGet gets an issue from the cache or GitHub for a given org, project, and number.
 func IssuesGet(ctx context.Context, p persist.Cacher, c *github.Client, t time.Time, org string, project string, num int) (*github.Issue, error) {
-	key := fmt.Sprintf("issue-%s-%s-%d", org, project, num)
+	key := issueKey(org, project, num)
 	val := p.Get(key, t)
 
 	if val != nil {
@@ -131,7 +131,7 @@ func IssuesGet(ctx context.Context, p persist.Cacher, c *github.Client, t time.T
 
 // IssuesListComments gets a list of comments in an issue from the cache or GitHub for a given org, project, and number.
 func IssuesListComments(ctx context.Context, p persist.Cacher, c *github.Client, t time.Time, org string, project string, num int) ([]*github.IssueComment, error) {
-	key := fmt.Sprintf("issue-comments-%s-%s-%d", org, project, num)
+	key := issueCommentsKey(org, project, num)
 	val := p.Get(key, t)
 
 	if val != nil {
@@ -160,3 +160,76 @@ func IssuesListComments(ctx context.Context, p persist.Cacher, c *github.Client,
 
 	return cs, p.Set(key, &persist.Blob{GHIssueComments: cs})
 }
+
+// GraphQLPullRequestsGet gets a pull request stored by GraphQLPullRequestsSet, or nil if there is none newer than t.
+func GraphQLPullRequestsGet(p persist.Cacher, t time.Time, org string, project string, num int) *github.PullRequest {
+	val := p.Get(graphQLPullRequestKey(org, project, num), t)
+	if val == nil {
+		return nil
+	}
+	return val.GHPullRequest
+}
+
+// GraphQLPullRequestsSet stores a pull request converted from the GraphQL API. It lacks most REST fields, so it is
+// stored apart from those PullRequestsGet returns.
+func GraphQLPullRequestsSet(p persist.Cacher, org string, project string, pr *github.PullRequest) error {
+	return p.Set(graphQLPullRequestKey(org, project, pr.GetNumber()), &persist.Blob{GHPullRequest: pr})
+}
+
+// PullRequestsSetFiles stores the complete list of files in a pull request.
+func PullRequestsSetFiles(p persist.Cacher, org string, project string, num int, fs []*github.CommitFile) error {
+	return p.Set(pullRequestFilesKey(org, project, num), &persist.Blob{GHCommitFiles: fs})
+}
+
+// PullRequestsSetComments stores the complete list of review comments in a pull request.
+func PullRequestsSetComments(p persist.Cacher, org string, project string, num int, cs []*github.PullRequestComment) error {
+	return p.Set(pullRequestCommentsKey(org, project, num), &persist.Blob{GHPullRequestComments: cs})
+}
+
+// IssuesSetComments stores the complete list of comments in an issue or pull request.
+func IssuesSetComments(p persist.Cacher, org string, project string, num int, cs []*github.IssueComment) error {
+	return p.Set(issueCommentsKey(org, project, num), &persist.Blob{GHIssueComments: cs})
+}
+
+// SyncGet returns when a repository was last synced for the incremental results identified by id, or the zero time
+// if it never was.
+func SyncGet(p persist.Cacher, org string, project string, id int64) time.Time {
+	val := p.Get(syncKey(org, project, id), time.Time{})
+	if val == nil {
+		return time.Time{}
+	}
+	return val.Created
+}
+
+// SyncSet records when a repository was last synced for the incremental results identified by id.
+func SyncSet(p persist.Cacher, org string, project string, id int64, t time.Time) error {
+	return p.Set(syncKey(org, project, id), &persist.Blob{Created: t})
+}
+
+func syncKey(org string, project string, id int64) string {
+	return fmt.Sprintf("sync-%s-%s-%d", org, project, id)
+}
+
+func pullRequestKey(org string, project string, num int) string {
+	return fmt.Sprintf("pr-%s-%s-%d", org, project, num)
+}
+
+func graphQLPullRequestKey(org string, project string, num int) string {
+	return fmt.Sprintf("gql-pr-%s-%s-%d", org, project, num)
+}
+
+func pullRequestFilesKey(org string, project string, num int) string {
+	return fmt.Sprintf("pr-listfiles-%s-%s-%d", org, project, num)
+}
+
+func pullRequestCommentsKey(org string, project string, num int) string {
+	return fmt.Sprintf("pr-comments-%s-%s-%d", org, project, num)
+}
+
+func issueKey(org string, project string, num int) string {
+	return fmt.Sprintf("issue-%s-%s-%d", org, project, num)
+}
+
+func issueCommentsKey(org string, project string, num int) string {
+	return fmt.Sprintf("issue-comments-%s-%s-%d", org, project, num)
+}
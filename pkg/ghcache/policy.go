@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghcache
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/triage-party/pkg/persist"
+)
+
+// DefaultTTL is how long cached objects are used before they are refetched, by object type.
+// With conditional requests, refetching an unchanged object does not count against the rate limit.
+var DefaultTTL = map[string]time.Duration{
+	"pr":             7 * 24 * time.Hour,
+	"gql-pr":         7 * 24 * time.Hour,
+	"pr-listfiles":   30 * 24 * time.Hour,
+	"pr-comments":    7 * 24 * time.Hour,
+	"issue":          7 * 24 * time.Hour,
+	"issue-comments": 7 * 24 * time.Hour,
+	"http":           7 * 24 * time.Hour, // Responses stored for conditional requests, with --http-cache
+}
+
+// ttlCacher treats cached objects as stale once they are older than the TTL for their type
+type ttlCacher struct {
+	persist.Cacher
+	ttl map[string]time.Duration
+}
+
+// WithTTL returns a cache in which objects are stale once older than the TTL for their type, as well as when
+// older than the time a caller asks for. Types are key prefixes, such as "pr" or "issue-comments", and the
+// longest matching type wins. A zero TTL never expires objects.
+func WithTTL(p persist.Cacher, ttl map[string]time.Duration) persist.Cacher {
+	return &ttlCacher{Cacher: p, ttl: ttl}
+}
+
+// Get returns a cached object that is fresh according to both t and the TTL policy
+func (c *ttlCacher) Get(key string, t time.Time) *persist.Blob {
+	if d := c.ttlFor(key); d > 0 {
+		if oldest := time.Now().Add(-d); oldest.After(t) {
+			t = oldest
+		}
+	}
+	return c.Cacher.Get(key, t)
+}
+
+func (c *ttlCacher) ttlFor(key string) time.Duration {
+	match := ""
+	for typ := range c.ttl {
+		if strings.HasPrefix(key, typ+"-") && len(typ) > len(match) {
+			match = typ
+		}
+	}
+	return c.ttl[match]
+}
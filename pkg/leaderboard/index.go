@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderboard
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// IndexEntry is a report listed on an index page
+type IndexEntry struct {
+	Title    string
+	Since    time.Time
+	Until    time.Time
+	Links    []Link   // The first link is used for the title
+	Warnings []string // Shown next to the entry, such as when its data is incomplete
+}
+
+// Link is a link to a file of a report
+type Link struct {
+	Name string
+	Href string
+}
+
+// RenderIndex returns an HTML formatted page linking to a set of reports
+func RenderIndex(title string, entries []IndexEntry) (string, error) {
+	tmpl, err := template.New("Index").Parse(indexTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsefiles: %v", err)
+	}
+
+	type entry struct {
+		IndexEntry
+		From  string
+		Until string
+	}
+
+	data := struct {
+		Title   string
+		Entries []entry
+	}{Title: title}
+
+	for _, e := range entries {
+		data.Entries = append(data.Entries, entry{IndexEntry: e, From: e.Since.Format(dateForm), Until: e.Until.Format(dateForm)})
+	}
+
+	var tpl bytes.Buffer
+	if err = tmpl.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+
+	return tpl.String(), nil
+}
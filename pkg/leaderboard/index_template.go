@@ -0,0 +1,138 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderboard
+
+const indexTmpl = `<html>
+<head>
+    <title>{{ .Title }}</title>
+    <link rel="preconnect" href="https://fonts.gstatic.com">
+    <link href="https://fonts.googleapis.com/css2?family=Open+Sans:wght@300;400;600;700&display=swap" rel="stylesheet">
+    <style>
+    body {
+       font-family: 'Open Sans', sans-serif;
+       background-color: #f7f7fa;
+       padding: 1em;
+    }
+
+    h1 {
+      color: rgba(66,133,244);
+    }
+
+    .report {
+        padding: 0.5em 1em;
+        margin: 0.5em 0;
+        background-color: #fff;
+        border: 2px solid rgba(66,133,244,0.25);
+    }
+
+    .report a.title {
+        font-weight: 600;
+        font-size: large;
+        color: rgba(23,90,201);
+    }
+
+    .subtitle, .files {
+      color: #666;
+      font-size: small;
+    }
+
+    .warning {
+        margin: 0.5em 0;
+        padding: 0.25em 0.5em;
+        font-size: small;
+        border: 2px solid rgba(219,68,55,0.5);
+        background-color: rgba(219,68,55,0.08);
+    }
+    </style>
+</head>
+<body>
+    <h1>{{ .Title }}</h1>
+{{ range .Entries }}
+    <div class="report">
+        <a class="title" href="{{ (index .Links 0).Href }}">{{ .Title }}</a>
+        <div class="subtitle">{{ .From }} &mdash; {{ .Until }}</div>
+        <div class="files">{{ range .Links }}<a href="{{ .Href }}">{{ .Name }}</a> {{ end }}</div>
+    {{ range .Warnings }}
+        <div class="warning">{{ . }}</div>
+    {{ end }}
+    </div>
+{{ end }}
+</body>
+</html>
+`
+
+const winnersTmpl = `<html>
+<head>
+    <title>{{ .Title }} - Winners</title>
+    <link rel="preconnect" href="https://fonts.gstatic.com">
+    <link href="https://fonts.googleapis.com/css2?family=Open+Sans:wght@300;400;600;700&display=swap" rel="stylesheet">
+    <style>
+    body {
+       font-family: 'Open Sans', sans-serif;
+       background-color: #f7f7fa;
+       padding: 1em;
+    }
+
+    h1 {
+      color: rgba(66,133,244);
+    }
+
+    table {
+        border-collapse: collapse;
+        background-color: #fff;
+    }
+
+    th, td {
+        padding: 0.4em 0.8em;
+        border: 1px solid rgba(66,133,244,0.25);
+        text-align: left;
+    }
+
+    th {
+        color: #333;
+    }
+
+    td a {
+        font-weight: 600;
+        color: rgba(23,90,201);
+    }
+
+    .subtitle, .count {
+      color: #666;
+      font-size: small;
+    }
+    </style>
+</head>
+<body>
+    <h1>{{ .Title }}</h1>
+    <table>
+        <tr>
+            <th>Period</th>
+        {{ range .Charts }}
+            <th>{{ . }}</th>
+        {{ end }}
+        </tr>
+    {{ range .Periods }}
+        <tr>
+            <td><a href="{{ .Href }}">{{ .Title }}</a><div class="subtitle">{{ .From }} &mdash; {{ .Until }}</div></td>
+        {{ range .Winners }}
+            <td>{{ if .Name }}{{ .Name }} <span class="count">({{ .Count }})</span>{{ else }}&mdash;{{ end }}</td>
+        {{ end }}
+        </tr>
+    {{ end }}
+    </table>
+</body>
+</html>
+`
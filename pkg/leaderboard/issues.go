@@ -42,7 +42,7 @@ func issueCloserChart(is []*repo.IssueSummary, users []string) chart {
 		ID:     "issueCloser",
 		Title:  "Top Closers",
 		Metric: "# of issues closed (excludes authored)",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -58,7 +58,7 @@ func commentWordsChart(cs []*repo.CommentSummary, _ []string) chart {
 		ID:     "commentWords",
 		Title:  "Most Helpful",
 		Metric: "# of words (excludes authored)",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -72,6 +72,6 @@ func commentsChart(cs []*repo.CommentSummary, _ []string) chart {
 		ID:     "comments",
 		Title:  "Most Active",
 		Metric: "# of comments",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
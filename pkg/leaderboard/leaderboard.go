@@ -16,15 +16,15 @@ package leaderboard
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
-	"strings"
 	"text/template"
 	"time"
 
+	"github.com/google/pullsheet/pkg/print"
 	"github.com/google/pullsheet/pkg/repo"
+	"github.com/google/pullsheet/pkg/timeseries"
 )
 
 const dateForm = "2006-01-02"
@@ -32,13 +32,32 @@ const dateForm = "2006-01-02"
 // TopX is how many items to include in graphs
 var TopX = 15
 
+// TrendTopX is how many of the top items of a graph to show the trend of
+var TrendTopX = 5
+
 // Options to use for rendering the leaderboard
 type Options struct {
 	Title          string
 	Since          time.Time
 	Until          time.Time
 	DisableCaching bool
+	Command        string // Command line which generated the leaderboard, shown unless HideCommand is set
 	HideCommand    bool
+	Warnings       []string       // Shown in a banner, such as when the data is incomplete
+	Links          []Link         // Navigation links shown below the title, such as to other periods
+	Trend          string         // If set, each chart shows the trend of its top items by day, week or month
+	Location       *time.Location // Time zone the summaries are dated in, for trends. Defaults to UTC
+	Compare        *Comparison    // If set, chart items show their change since an earlier window
+}
+
+// Comparison is the activity of an earlier window to compare a leaderboard with
+type Comparison struct {
+	Since    time.Time
+	Until    time.Time
+	PRs      []*repo.PRSummary
+	Reviews  []*repo.ReviewSummary
+	Issues   []*repo.IssueSummary
+	Comments []*repo.CommentSummary
 }
 
 type category struct {
@@ -52,11 +71,54 @@ type chart struct {
 	Object string
 	Metric string
 	Items  []item
+	Trend  string // JSON data table of the top items by bucket, if trends are shown
 }
 
 type item struct {
-	Name  string
-	Count int
+	Name      string
+	Count     int
+	Rank      int  // Position in the chart, from 1
+	Compared  bool // Whether the chart is compared with an earlier window
+	PrevRank  int  // Position in the chart for the earlier window, or 0 if the item was not in it
+	PrevCount int
+}
+
+// Label returns the name of the item, along with its change in rank if compared with an earlier window
+func (i item) Label() string {
+	switch {
+	case !i.Compared:
+		return i.Name
+	case i.PrevRank == 0:
+		return i.Name + " (new)"
+	case i.PrevRank > i.Rank:
+		return fmt.Sprintf("%s \u25b2%d", i.Name, i.PrevRank-i.Rank)
+	case i.PrevRank < i.Rank:
+		return fmt.Sprintf("%s \u25bc%d", i.Name, i.Rank-i.PrevRank)
+	}
+	return i.Name + " ="
+}
+
+// Annotation returns the count of the item, along with its change if compared with an earlier window
+func (i item) Annotation() string {
+	if !i.Compared || i.PrevCount == 0 {
+		return fmt.Sprintf("%d", i.Count)
+	}
+	return fmt.Sprintf("%d (%+d%%)", i.Count, (i.Count-i.PrevCount)*100/i.PrevCount)
+}
+
+// page is the contents of a leaderboard
+type page struct {
+	Title          string
+	From           string
+	Until          string
+	DisableCaching bool
+	Command        string
+	HideCommand    bool
+	Warnings       []string
+	Links          []Link
+	CompareFrom    string
+	CompareUntil   string
+	Categories     []category
 }
 
 // Render returns an HTML formatted leaderboard page
@@ -67,63 +129,236 @@ func Render(options Options, users []string, prs []*repo.PRSummary, reviews []*r
 		return "", fmt.Errorf("parsefiles: %v", err)
 	}
 
-	data := struct {
-		Title          string
-		From           string
-		Until          string
-		DisableCaching bool
-		Command        string
-		HideCommand    bool
-		Categories     []category
-	}{
+	data, err := newPage(options, users, prs, reviews, issues, comments)
+	if err != nil {
+		return "", err
+	}
+
+	var tpl bytes.Buffer
+	if err = tmpl.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+
+	out := tpl.String()
+	return out, nil
+}
+
+// RenderMarkdown returns the leaderboard as GitHub-flavored Markdown, with a ranked list per chart. Trends and
+// navigation links are not shown.
+func RenderMarkdown(options Options, users []string, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) (string, error) {
+	tmpl, err := template.New("Markdown").Funcs(template.FuncMap{"md": print.MarkdownEscape}).Parse(markdownTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse: %v", err)
+	}
+
+	options.Trend = ""
+	data, err := newPage(options, users, prs, reviews, issues, comments)
+	if err != nil {
+		return "", err
+	}
+
+	var tpl bytes.Buffer
+	if err = tmpl.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+	return tpl.String(), nil
+}
+
+// Row is an item of a leaderboard chart, such as for a spreadsheet of the charts
+type Row struct {
+	Category  string
+	Chart     string
+	Metric    string
+	Rank      int
+	Name      string
+	Count     int
+	PrevRank  int // Position in the chart for the window compared with, or 0 if none
+	PrevCount int
+}
+
+// Rows returns the items of each leaderboard chart, as Render shows them. Trends are not included.
+func Rows(options Options, users []string, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) ([]*Row, error) {
+	options.Trend = ""
+	data, err := newPage(options, users, prs, reviews, issues, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*Row{}
+	for _, c := range data.Categories {
+		for _, ch := range c.Charts {
+			for _, it := range ch.Items {
+				rows = append(rows, &Row{
+					Category:  c.Title,
+					Chart:     ch.Title,
+					Metric:    ch.Metric,
+					Rank:      it.Rank,
+					Name:      it.Name,
+					Count:     it.Count,
+					PrevRank:  it.PrevRank,
+					PrevCount: it.PrevCount,
+				})
+			}
+		}
+	}
+	return rows, nil
+}
+
+// newPage returns the contents of a leaderboard, with charts truncated to TopX items
+func newPage(options Options, users []string, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) (*page, error) {
+	data := &page{
 		Title:          options.Title,
 		From:           options.Since.Format(dateForm),
 		Until:          options.Until.Format(dateForm),
 		DisableCaching: options.DisableCaching,
-		Command:        filepath.Base(os.Args[0]) + " " + strings.Join(os.Args[1:], " "),
-		HideCommand:    options.HideCommand,
-		Categories: []category{
-			{
-				Title: "Reviewers",
-				Charts: []chart{
-					reviewsChart(reviews, users),
-					reviewWordsChart(reviews, users),
-					reviewCommentsChart(reviews, users),
-				},
+		Command:        options.Command,
+		HideCommand:    options.HideCommand || options.Command == "",
+		Warnings:       options.Warnings,
+		Links:          options.Links,
+		Categories:     categories(users, prs, reviews, issues, comments),
+	}
+
+	if cmp := options.Compare; cmp != nil {
+		data.CompareFrom = cmp.Since.Format(dateForm)
+		data.CompareUntil = cmp.Until.Format(dateForm)
+		compare(data.Categories, categories(users, cmp.PRs, cmp.Reviews, cmp.Issues, cmp.Comments))
+	}
+
+	if options.Trend != "" {
+		buckets, err := timeseries.Split(options.Trend, options.Location, prs, reviews, issues, comments)
+		if err != nil {
+			return nil, fmt.Errorf("trend: %v", err)
+		}
+		if err := addTrends(data.Categories, users, buckets); err != nil {
+			return nil, fmt.Errorf("trend: %v", err)
+		}
+	}
+
+	for i := range data.Categories {
+		for j := range data.Categories[i].Charts {
+			ch := &data.Categories[i].Charts[j]
+			if len(ch.Items) > TopX {
+				ch.Items = ch.Items[:TopX]
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// categories returns the charts of a leaderboard, by category
+func categories(users []string, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) []category {
+	return []category{
+		{
+			Title: "Reviewers",
+			Charts: []chart{
+				reviewsChart(reviews, users),
+				reviewWordsChart(reviews, users),
+				reviewCommentsChart(reviews, users),
 			},
-			{
-				Title: "Pull Requests",
-				Charts: []chart{
-					mergeChart(prs, users),
-					deltaChart(prs, users),
-					sizeChart(prs, users),
-				},
+		},
+		{
+			Title: "Pull Requests",
+			Charts: []chart{
+				mergeChart(prs, users),
+				deltaChart(prs, users),
+				sizeChart(prs, users),
 			},
-			{
-				Title: "Issues",
-				Charts: []chart{
-					commentsChart(comments, users),
-					commentWordsChart(comments, users),
-					issueCloserChart(issues, users),
-				},
+		},
+		{
+			Title: "Issues",
+			Charts: []chart{
+				commentsChart(comments, users),
+				commentWordsChart(comments, users),
+				issueCloserChart(issues, users),
 			},
 		},
 	}
+}
 
-	var tpl bytes.Buffer
-	if err = tmpl.Execute(&tpl, data); err != nil {
-		return "", fmt.Errorf("execute: %w", err)
+// compare records the rank and count of each chart item in prev, the charts of an earlier window
+func compare(cats []category, prev []category) {
+	for i := range cats {
+		for j := range cats[i].Charts {
+			before := map[string]item{}
+			for _, it := range prev[i].Charts[j].Items {
+				before[it.Name] = it
+			}
+
+			items := cats[i].Charts[j].Items
+			for k := range items {
+				items[k].Compared = true
+				if b, ok := before[items[k].Name]; ok {
+					items[k].PrevRank = b.Rank
+					items[k].PrevCount = b.Count
+				}
+			}
+		}
 	}
+}
 
-	out := tpl.String()
-	return out, nil
+// addTrends adds the trend of the top items of each chart over buckets
+func addTrends(cats []category, users []string, buckets []*timeseries.Bucket) error {
+	// chart ID -> item name -> count, for each bucket
+	counts := []map[string]map[string]int{}
+	for _, b := range buckets {
+		byChart := map[string]map[string]int{}
+		for _, c := range categories(users, b.PRs, b.Reviews, b.Issues, b.Comments) {
+			for _, ch := range c.Charts {
+				byChart[ch.ID] = map[string]int{}
+				for _, it := range ch.Items {
+					byChart[ch.ID][it.Name] = it.Count
+				}
+			}
+		}
+		counts = append(counts, byChart)
+	}
+
+	for i := range cats {
+		for j := range cats[i].Charts {
+			ch := &cats[i].Charts[j]
+			top := ch.Items
+			if len(top) > TrendTopX {
+				top = top[:TrendTopX]
+			}
+			if len(top) == 0 {
+				continue
+			}
+
+			header := []interface{}{"Period"}
+			for _, it := range top {
+				header = append(header, it.Name)
+			}
+			table := [][]interface{}{header}
+			for k, b := range buckets {
+				row := []interface{}{b.Name}
+				for _, it := range top {
+					row = append(row, counts[k][ch.ID][it.Name])
+				}
+				table = append(table, row)
+			}
+
+			js, err := json.Marshal(table)
+			if err != nil {
+				return err
+			}
+			ch.Trend = string(js)
+		}
+	}
+	return nil
 }
 
-func topItems(items []item) []item {
-	sort.Slice(items, func(i, j int) bool { return items[i].Count > items[j].Count })
+// sortItems sorts items by count, and then name, and ranks them. Charts are truncated to TopX items when rendered.
+func sortItems(items []item) []item {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
 
-	if len(items) > TopX {
-		items = items[:TopX]
+	for i := range items {
+		items[i].Rank = i + 1
 	}
 	return items
 }
@@ -98,11 +98,34 @@ const leaderboardTmpl = `<html>
         text-align: center;
     }
 
+    .nav {
+        margin-top: 0.5em;
+        font-size: small;
+    }
+
+    .nav a {
+        color: rgba(23,90,201);
+        margin-right: 1em;
+    }
+
+    .warning {
+        margin: 1em 0;
+        padding: 0.5em 1em;
+        border: 2px solid rgba(219,68,55,0.5);
+        background-color: rgba(219,68,55,0.08);
+    }
+
     </style>
 </head>
 <body>
     <h1>{{ .Title }}</h1>
-    <div class="subtitle">{{.From}} &mdash; {{.Until}}</div>
+    <div class="subtitle">{{.From}} &mdash; {{.Until}}{{ if .CompareFrom }}, compared with {{.CompareFrom}} &mdash; {{.CompareUntil}}{{ end }}</div>
+{{ if .Links }}
+    <div class="nav">{{ range .Links }}<a href="{{ .Href }}">{{ .Name }}</a> {{ end }}</div>
+{{ end }}
+{{ range .Warnings }}
+    <div class="warning">{{ . | html }}</div>
+{{ end }}
 {{ if not .HideCommand }}
     <h2 class="cli">Command-line</h2>
     <pre>{{.Command}}</pre>
@@ -121,7 +144,7 @@ const leaderboardTmpl = `<html>
                 function draw{{.ID}}() {
                     var data = new google.visualization.arrayToDataTable([
                     [{label:'{{.Object}}',type:'string'},{label: '{{.Metric}}', type: 'number'}, { role: 'annotation' }],
-                    {{ range .Items }}["{{.Name}}", {{.Count}}, "{{.Count}}"],
+                    {{ range .Items }}["{{.Label}}", {{.Count}}, "{{.Annotation}}"],
                     {{ end }}
                     ]);
 
@@ -142,6 +165,25 @@ const leaderboardTmpl = `<html>
                    chart.draw(data, options);
                 };
             </script>
+            {{ if .Trend }}
+            <div id="trend_{{ .ID }}" style="width: 450px; height: 150px;"></div>
+            <script type="text/javascript">
+                google.charts.setOnLoadCallback(drawTrend{{ .ID }});
+
+                function drawTrend{{ .ID }}() {
+                    var data = new google.visualization.arrayToDataTable({{ .Trend }});
+                    var options = {
+                        legend: { position: "bottom", textStyle: { fontSize: 10 } },
+                        hAxis: { textStyle: { fontSize: 10 } },
+                        vAxis: { textStyle: { fontSize: 10 }, minValue: 0 },
+                        chartArea: { width: "85%", height: "60%" },
+                    };
+
+                    var chart = new google.visualization.LineChart(document.getElementById('trend_{{ .ID }}'));
+                    chart.draw(data, options);
+                };
+            </script>
+            {{ end }}
             </div>
         {{ end }}
     {{ end}}
@@ -0,0 +1,35 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderboard
+
+const markdownTmpl = `# {{ md .Title }}
+
+{{ .From }} to {{ .Until }}{{ if .CompareFrom }}, compared with {{ .CompareFrom }} to {{ .CompareUntil }}{{ end }}
+{{ range .Warnings }}
+> **Warning:** {{ md . }}
+{{ end }}{{ if not .HideCommand }}
+` + "```" + `
+{{ .Command }}
+` + "```" + `
+{{ end }}{{ range .Categories }}
+## {{ .Title }}
+{{ range .Charts }}
+### {{ .Title }}
+
+_{{ md .Metric }}_
+{{ range .Items }}
+{{ .Rank }}. {{ md .Label }}: {{ .Annotation }}{{ else }}
+None.{{ end }}
+{{ end }}{{ end }}`
@@ -28,7 +28,7 @@ func mergeChart(prs []*repo.PRSummary, _ []string) chart {
 		ID:     "prCounts",
 		Title:  "Most Active",
 		Metric: "# of Pull Requests Merged",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -42,7 +42,7 @@ func deltaChart(prs []*repo.PRSummary, _ []string) chart {
 		ID:     "prDeltas",
 		Title:  "Big Movers",
 		Metric: "Lines of code (delta)",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -66,6 +66,6 @@ func sizeChart(prs []*repo.PRSummary, _ []string) chart {
 		ID:     "prSize",
 		Title:  "Most difficult to review",
 		Metric: "Average PR size (added+changed)",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
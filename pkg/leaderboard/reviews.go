@@ -28,7 +28,7 @@ func reviewsChart(reviews []*repo.ReviewSummary, _ []string) chart {
 		ID:     "reviewCounts",
 		Title:  "Most Influential",
 		Metric: "# of Merged PRs reviewed",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -42,7 +42,7 @@ func reviewCommentsChart(reviews []*repo.ReviewSummary, _ []string) chart {
 		ID:     "reviewComments",
 		Title:  "Most Demanding",
 		Metric: "# of Review Comments in merged PRs",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
 
@@ -56,6 +56,6 @@ func reviewWordsChart(reviews []*repo.ReviewSummary, _ []string) chart {
 		ID:     "reviewWords",
 		Title:  "Most Helpful",
 		Metric: "# of words written in merged PRs",
-		Items:  topItems(mapToItems(uMap)),
+		Items:  sortItems(mapToItems(uMap)),
 	}
 }
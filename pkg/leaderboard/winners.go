@@ -0,0 +1,96 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderboard
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// Winner is the top entry of a leaderboard chart
+type Winner struct {
+	Name  string // Empty if the chart has no entries
+	Count int
+}
+
+// PeriodWinners are the winners of each chart of the leaderboard for a period
+type PeriodWinners struct {
+	Title   string
+	Href    string // Link to the period's leaderboard
+	Since   time.Time
+	Until   time.Time
+	Winners []Winner // In the order returned by ChartTitles
+}
+
+// ChartTitles returns the titles of the charts on a leaderboard, in order
+func ChartTitles() []string {
+	titles := []string{}
+	for _, c := range categories(nil, nil, nil, nil, nil) {
+		for _, ch := range c.Charts {
+			titles = append(titles, ch.Title)
+		}
+	}
+	return titles
+}
+
+// Winners returns the winners of each chart of a leaderboard, in the order returned by ChartTitles
+func Winners(users []string, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) []Winner {
+	ws := []Winner{}
+	for _, c := range categories(users, prs, reviews, issues, comments) {
+		for _, ch := range c.Charts {
+			w := Winner{}
+			if len(ch.Items) > 0 {
+				w = Winner{Name: ch.Items[0].Name, Count: ch.Items[0].Count}
+			}
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
+
+// RenderWinners returns an HTML formatted page of the winners of each chart by period
+func RenderWinners(title string, periods []PeriodWinners) (string, error) {
+	tmpl, err := template.New("Winners").Parse(winnersTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsefiles: %v", err)
+	}
+
+	type period struct {
+		PeriodWinners
+		From  string
+		Until string
+	}
+
+	data := struct {
+		Title   string
+		Charts  []string
+		Periods []period
+	}{Title: title, Charts: ChartTitles()}
+
+	for _, p := range periods {
+		data.Periods = append(data.Periods, period{PeriodWinners: p, From: p.Since.Format(dateForm), Until: p.Until.Format(dateForm)})
+	}
+
+	var tpl bytes.Buffer
+	if err = tmpl.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+
+	return tpl.String(), nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package period divides time into calendar periods, such as months and quarters.
+package period
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is a calendar period
+type Period struct {
+	Name  string    // Such as 2021-03 or 2021-Q1
+	Since time.Time // First instant of the period
+	Until time.Time // First instant after the period
+}
+
+// Last returns the last day of the period, as displayed on leaderboards
+func (p Period) Last() time.Time {
+	return p.Until.AddDate(0, 0, -1)
+}
+
+// Units are the supported lengths of a period
+var Units = []string{"day", "week", "month", "quarter", "year"}
+
+// Start returns the period of the given unit containing t
+func Start(t time.Time, unit string) (Period, error) {
+	y, m, _ := t.Date()
+	switch unit {
+	case "day":
+		since := time.Date(y, m, t.Day(), 0, 0, 0, 0, t.Location())
+		return Period{Name: since.Format("2006-01-02"), Since: since, Until: since.AddDate(0, 0, 1)}, nil
+	case "week":
+		y, w := t.ISOWeek()
+		return isoWeek(y, w, t.Location()), nil
+	case "month":
+		since := time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+		return Period{Name: since.Format("2006-01"), Since: since, Until: since.AddDate(0, 1, 0)}, nil
+	case "quarter":
+		q := (int(m) - 1) / 3
+		since := time.Date(y, time.Month(q*3+1), 1, 0, 0, 0, 0, t.Location())
+		return Period{Name: fmt.Sprintf("%d-Q%d", y, q+1), Since: since, Until: since.AddDate(0, 3, 0)}, nil
+	case "year":
+		since := time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+		return Period{Name: since.Format("2006"), Since: since, Until: since.AddDate(1, 0, 0)}, nil
+	}
+	return Period{}, fmt.Errorf("unknown period %q, must be one of %v", unit, Units)
+}
+
+// Range returns the periods of the given unit which overlap since to until, oldest first
+func Range(since time.Time, until time.Time, unit string) ([]Period, error) {
+	ps := []Period{}
+	p, err := Start(since, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.Since.Before(until) {
+		ps = append(ps, p)
+		if p, err = Start(p.Until, unit); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// Parse returns the period described by an expression, in the time zone of now:
+//
+//	2024-Q3, 2024-03, 2024-W12 (ISO week), 2024, fy2025
+//	this-week, last-week, this-month, last-month, this-quarter, last-quarter, this-year, last-year, this-fy, last-fy
+//
+// Fiscal years start on the first day of fyStart, and are named after the calendar year in which they end.
+func Parse(expr string, now time.Time, fyStart time.Month) (Period, error) {
+	e := strings.ToLower(strings.TrimSpace(expr))
+	loc := now.Location()
+
+	if rel, unit, ok := strings.Cut(e, "-"); ok && (rel == "this" || rel == "last") {
+		p, err := containing(now, unit, fyStart)
+		if err != nil {
+			return Period{}, fmt.Errorf("period %q: %v", expr, err)
+		}
+		if rel == "last" {
+			return containing(p.Since.Add(-time.Nanosecond), unit, fyStart)
+		}
+		return p, nil
+	}
+
+	if m := fyExpr.FindStringSubmatch(e); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		return fiscalYear(y, fyStart, loc), nil
+	}
+
+	if m := quarterExpr.FindStringSubmatch(e); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		q, _ := strconv.Atoi(m[2])
+		return Start(time.Date(y, time.Month(q*3-2), 1, 0, 0, 0, 0, loc), "quarter")
+	}
+
+	if m := weekExpr.FindStringSubmatch(e); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		w, _ := strconv.Atoi(m[2])
+		p := isoWeek(y, w, loc)
+		if wy, ww := p.Since.ISOWeek(); wy != y || ww != w {
+			return Period{}, fmt.Errorf("period %q: %d has no week %d", expr, y, w)
+		}
+		return p, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01", e, loc); err == nil {
+		return Start(t, "month")
+	}
+
+	if t, err := time.ParseInLocation("2006", e, loc); err == nil {
+		return containing(t, "year", fyStart)
+	}
+
+	return Period{}, fmt.Errorf("unknown period %q: expected forms such as 2024-Q3, 2024-03, 2024-W12, 2024, fy2025 or last-month", expr)
+}
+
+var (
+	fyExpr      = regexp.MustCompile(`^fy(\d{4})$`)
+	quarterExpr = regexp.MustCompile(`^(\d{4})-q([1-4])$`)
+	weekExpr    = regexp.MustCompile(`^(\d{4})-w(\d{1,2})$`)
+)
+
+// containing returns the period of a unit which contains t, as Start does, also supporting fiscal years as "fy"
+func containing(t time.Time, unit string, fyStart time.Month) (Period, error) {
+	if unit != "fy" {
+		return Start(t, unit)
+	}
+
+	y := t.Year()
+	if fyStart != time.January && t.Month() >= fyStart {
+		y++
+	}
+	return fiscalYear(y, fyStart, t.Location()), nil
+}
+
+// fiscalYear returns the fiscal year ending in year y, or the calendar year y if fiscal years start in January
+func fiscalYear(y int, fyStart time.Month, loc *time.Location) Period {
+	since := time.Date(y, fyStart, 1, 0, 0, 0, 0, loc)
+	if fyStart != time.January {
+		since = since.AddDate(-1, 0, 0)
+	}
+	return Period{Name: fmt.Sprintf("FY%d", y), Since: since, Until: since.AddDate(1, 0, 0)}
+}
+
+// isoWeek returns ISO week w of year y, which starts on a Monday
+func isoWeek(y int, w int, loc *time.Location) Period {
+	// January 4th is always in week 1
+	jan4 := time.Date(y, time.January, 4, 0, 0, 0, 0, loc)
+	since := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(w-1)*7)
+	return Period{Name: fmt.Sprintf("%d-W%02d", y, w), Since: since, Until: since.AddDate(0, 0, 7)}
+}
+
+// ParseMonth returns the month named by s, such as "april", "apr" or "4"
+func ParseMonth(s string) (time.Month, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("invalid month %q", s)
+		}
+		return time.Month(n), nil
+	}
+
+	// Month names are matched case-sensitively
+	name := strings.ToUpper(s[:min(len(s), 1)]) + strings.ToLower(s[min(len(s), 1):])
+	for _, layout := range []string{"January", "Jan"} {
+		if t, err := time.Parse(layout, name); err == nil {
+			return t.Month(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid month %q", s)
+}
+
+// Previous returns the window of the same length immediately before since to until, which is inclusive. Windows
+// of whole calendar months, such as quarters and fiscal years, step back by calendar months.
+func Previous(since time.Time, until time.Time) (time.Time, time.Time) {
+	end := until
+	if e := until.Add(time.Nanosecond); midnight(e) {
+		end = e
+	}
+
+	if monthStart(since) && monthStart(end) {
+		months := (end.Year()-since.Year())*12 + int(end.Month()) - int(since.Month())
+		return since.AddDate(0, -months, 0), since.Add(-time.Nanosecond)
+	}
+
+	return since.Add(-end.Sub(since)), since.Add(-time.Nanosecond)
+}
+
+// midnight returns whether t is the first instant of a day
+func midnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// monthStart returns whether t is the first instant of a month
+func monthStart(t time.Time) bool {
+	return t.Day() == 1 && midnight(t)
+}
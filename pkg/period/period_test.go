@@ -0,0 +1,200 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	mid2024 := time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		now     time.Time
+		fyStart time.Month
+		name    string
+		since   time.Time
+		until   time.Time
+	}{
+		{expr: "2024-Q3", now: mid2024, name: "2024-Q3", since: date(2024, time.July, 1), until: date(2024, time.October, 1)},
+		{expr: "2024-q4", now: mid2024, name: "2024-Q4", since: date(2024, time.October, 1), until: date(2025, time.January, 1)},
+		{expr: "2024-03", now: mid2024, name: "2024-03", since: date(2024, time.March, 1), until: date(2024, time.April, 1)},
+		{expr: "2024", now: mid2024, name: "2024", since: date(2024, time.January, 1), until: date(2025, time.January, 1)},
+
+		// ISO weeks start on Monday, and week 1 is the week holding January 4th
+		{expr: "2024-W12", now: mid2024, name: "2024-W12", since: date(2024, time.March, 18), until: date(2024, time.March, 25)},
+		{expr: "2024-w1", now: mid2024, name: "2024-W01", since: date(2024, time.January, 1), until: date(2024, time.January, 8)},
+		{expr: "2021-W01", now: mid2024, name: "2021-W01", since: date(2021, time.January, 4), until: date(2021, time.January, 11)},
+		{expr: "2025-W01", now: mid2024, name: "2025-W01", since: date(2024, time.December, 30), until: date(2025, time.January, 6)},
+		{expr: "2026-W01", now: mid2024, name: "2026-W01", since: date(2025, time.December, 29), until: date(2026, time.January, 5)},
+		{expr: "2020-W53", now: mid2024, name: "2020-W53", since: date(2020, time.December, 28), until: date(2021, time.January, 4)},
+		{expr: "2026-W53", now: mid2024, name: "2026-W53", since: date(2026, time.December, 28), until: date(2027, time.January, 4)},
+
+		// Fiscal years are named after the calendar year in which they end
+		{expr: "fy2025", now: mid2024, fyStart: time.April, name: "FY2025", since: date(2024, time.April, 1), until: date(2025, time.April, 1)},
+		{expr: "FY2025", now: mid2024, fyStart: time.October, name: "FY2025", since: date(2024, time.October, 1), until: date(2025, time.October, 1)},
+		{expr: "fy2025", now: mid2024, fyStart: time.January, name: "FY2025", since: date(2025, time.January, 1), until: date(2026, time.January, 1)},
+
+		{expr: "this-week", now: mid2024, name: "2024-W20", since: date(2024, time.May, 13), until: date(2024, time.May, 20)},
+		{expr: "last-week", now: date(2021, time.January, 6), name: "2020-W53", since: date(2020, time.December, 28), until: date(2021, time.January, 4)},
+		{expr: "this-month", now: mid2024, name: "2024-05", since: date(2024, time.May, 1), until: date(2024, time.June, 1)},
+		{expr: "last-month", now: mid2024, name: "2024-04", since: date(2024, time.April, 1), until: date(2024, time.May, 1)},
+		{expr: "last-month", now: date(2024, time.January, 1), name: "2023-12", since: date(2023, time.December, 1), until: date(2024, time.January, 1)},
+		{expr: "this-quarter", now: mid2024, name: "2024-Q2", since: date(2024, time.April, 1), until: date(2024, time.July, 1)},
+		{expr: "last-quarter", now: mid2024, name: "2024-Q1", since: date(2024, time.January, 1), until: date(2024, time.April, 1)},
+		{expr: "last-quarter", now: date(2024, time.February, 29), name: "2023-Q4", since: date(2023, time.October, 1), until: date(2024, time.January, 1)},
+		{expr: "this-year", now: mid2024, name: "2024", since: date(2024, time.January, 1), until: date(2025, time.January, 1)},
+		{expr: " Last-Year ", now: mid2024, name: "2023", since: date(2023, time.January, 1), until: date(2024, time.January, 1)},
+		{expr: "this-fy", now: mid2024, fyStart: time.April, name: "FY2025", since: date(2024, time.April, 1), until: date(2025, time.April, 1)},
+		{expr: "this-fy", now: date(2024, time.March, 31), fyStart: time.April, name: "FY2024", since: date(2023, time.April, 1), until: date(2024, time.April, 1)},
+		{expr: "last-fy", now: date(2024, time.October, 1), fyStart: time.October, name: "FY2024", since: date(2023, time.October, 1), until: date(2024, time.October, 1)},
+		{expr: "last-fy", now: mid2024, fyStart: time.January, name: "FY2023", since: date(2023, time.January, 1), until: date(2024, time.January, 1)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr+"@"+tc.now.Format("2006-01-02"), func(t *testing.T) {
+			fyStart := tc.fyStart
+			if fyStart == 0 {
+				fyStart = time.January
+			}
+
+			p, err := Parse(tc.expr, tc.now, fyStart)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.expr, err)
+			}
+			if p.Name != tc.name || !p.Since.Equal(tc.since) || !p.Until.Equal(tc.until) {
+				t.Errorf("Parse(%q) = %s %s to %s, want %s %s to %s", tc.expr, p.Name, p.Since, p.Until, tc.name, tc.since, tc.until)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"", "2024-Q5", "2024-Q0", "2021-W53", "2024-W00", "2024-W54", "2024-13", "next-month", "this-decade", "fy24", "yesterday"} {
+		if p, err := Parse(expr, now, time.January); err == nil {
+			t.Errorf("Parse(%q) = %s %s to %s, want error", expr, p.Name, p.Since, p.Until)
+		}
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+
+	// Late on March 31st in Los Angeles is already April in UTC
+	now := time.Date(2024, time.March, 31, 22, 0, 0, 0, loc)
+	p, err := Parse("this-month", now, time.January)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	since := time.Date(2024, time.March, 1, 0, 0, 0, 0, loc)
+	until := time.Date(2024, time.April, 1, 0, 0, 0, 0, loc)
+	if p.Name != "2024-03" || !p.Since.Equal(since) || !p.Until.Equal(until) {
+		t.Errorf("Parse(this-month) = %s %s to %s, want 2024-03 %s to %s", p.Name, p.Since, p.Until, since, until)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	end := func(y int, m time.Month, d int) time.Time {
+		return date(y, m, d).AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	tests := []struct {
+		name      string
+		since     time.Time
+		until     time.Time
+		wantSince time.Time
+		wantUntil time.Time
+	}{
+		{"quarter", date(2024, time.July, 1), end(2024, time.September, 30), date(2024, time.April, 1), end(2024, time.June, 30)},
+		{"first quarter", date(2024, time.January, 1), end(2024, time.March, 31), date(2023, time.October, 1), end(2023, time.December, 31)},
+		{"month after leap february", date(2024, time.March, 1), end(2024, time.March, 31), date(2024, time.February, 1), end(2024, time.February, 29)},
+		{"month after short month", date(2023, time.March, 1), end(2023, time.March, 31), date(2023, time.February, 1), end(2023, time.February, 28)},
+		{"fiscal year", date(2024, time.April, 1), end(2025, time.March, 31), date(2023, time.April, 1), end(2024, time.March, 31)},
+		{"iso week", date(2024, time.March, 18), end(2024, time.March, 24), date(2024, time.March, 11), end(2024, time.March, 17)},
+		{"days across leap day", date(2024, time.March, 10), end(2024, time.March, 19), date(2024, time.February, 29), end(2024, time.March, 9)},
+		{"partial day", date(2024, time.January, 1), date(2024, time.January, 1).Add(12 * time.Hour), date(2023, time.December, 31).Add(12 * time.Hour), end(2023, time.December, 31)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			since, until := Previous(tc.since, tc.until)
+			if !since.Equal(tc.wantSince) || !until.Equal(tc.wantUntil) {
+				t.Errorf("Previous(%s, %s) = %s to %s, want %s to %s", tc.since, tc.until, since, until, tc.wantSince, tc.wantUntil)
+			}
+		})
+	}
+}
+
+func TestParseMonth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Month
+	}{
+		{"april", time.April},
+		{"Apr", time.April},
+		{"APRIL", time.April},
+		{"4", time.April},
+		{"12", time.December},
+		{"sept", 0},
+		{"13", 0},
+		{"0", 0},
+		{"", 0},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseMonth(tc.in)
+		if tc.want == 0 {
+			if err == nil {
+				t.Errorf("ParseMonth(%q) = %s, want error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ParseMonth(%q) = %s, %v, want %s", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	ps, err := Range(date(2024, time.January, 15), date(2024, time.March, 10), "month")
+	if err != nil {
+		t.Fatalf("Range returned error: %v", err)
+	}
+
+	names := []string{}
+	for _, p := range ps {
+		names = append(names, p.Name)
+	}
+	want := []string{"2024-01", "2024-02", "2024-03"}
+	if len(names) != len(want) {
+		t.Fatalf("Range = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Range = %v, want %v", names, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package print
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// flattenSeparator joins the lines of multi-line fields when CSV output is flattened
+const flattenSeparator = " | "
+
+// CSVOptions customize CSV output. The zero value is the default output, with a column per field in struct order.
+type CSVOptions struct {
+	Columns        []string // Columns to include, in this order. Defaults to every field
+	ExcludeColumns []string // Columns to leave out
+	Sort           []string // Columns to sort rows by, descending if prefixed with "-"
+	Flatten        bool     // Whether to join the lines of multi-line fields, such as Description and Files, with " | "
+	Delimiter      rune     // Defaults to a comma
+	BOM            bool     // Whether to start with a UTF-8 byte order mark, so that Excel reads the output as UTF-8
+	Comments       bool     // Whether to precede the header with metadata as "#" lines, rather than logging it
+}
+
+// ParseDelimiter returns the delimiter named by s: comma, tab, semicolon, or a single character
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "", "comma":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "semicolon":
+		return ';', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q: must be comma, tab, semicolon or a single character", s)
+	}
+	return r, nil
+}
+
+// rewrites returns whether the options change the rows or columns of default output
+func (o CSVOptions) rewrites() bool {
+	return len(o.Columns) > 0 || len(o.ExcludeColumns) > 0 || len(o.Sort) > 0 || o.Flatten ||
+		(o.Delimiter != 0 && o.Delimiter != ',')
+}
+
+// formatCSV rewrites default CSV output, with a header row, as the options specify. The BOM is left to the caller,
+// as it precedes any metadata.
+func formatCSV(out string, o CSVOptions) (string, error) {
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("csv: %v", err)
+	}
+	if len(records) == 0 {
+		return out, nil
+	}
+	header, rows := records[0], records[1:]
+
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(h)] = i
+	}
+	column := func(name string) (int, error) {
+		i, ok := index[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown column %q, must be one of %s", name, strings.Join(header, ", "))
+		}
+		return i, nil
+	}
+
+	if err := sortRows(rows, o.Sort, column); err != nil {
+		return "", err
+	}
+
+	cols := []int{}
+	if len(o.Columns) == 0 {
+		for i := range header {
+			cols = append(cols, i)
+		}
+	}
+	for _, name := range o.Columns {
+		i, err := column(name)
+		if err != nil {
+			return "", err
+		}
+		cols = append(cols, i)
+	}
+
+	excluded := map[int]bool{}
+	for _, name := range o.ExcludeColumns {
+		i, err := column(name)
+		if err != nil {
+			return "", err
+		}
+		excluded[i] = true
+	}
+
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if o.Delimiter != 0 {
+		w.Comma = o.Delimiter
+	}
+	for _, r := range append([][]string{header}, rows...) {
+		out := []string{}
+		for _, i := range cols {
+			if excluded[i] {
+				continue
+			}
+			v := r[i]
+			if o.Flatten {
+				v = flatten(v)
+			}
+			out = append(out, v)
+		}
+		if err := w.Write(out); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return sb.String(), w.Error()
+}
+
+// sortRows sorts rows by columns, descending for those prefixed with "-". Values which are all numbers are
+// compared as numbers, and rows which compare equal keep their order.
+func sortRows(rows [][]string, by []string, column func(string) (int, error)) error {
+	type key struct {
+		col  int
+		desc bool
+	}
+
+	keys := []key{}
+	for _, name := range by {
+		k := key{}
+		if strings.HasPrefix(name, "-") {
+			k.desc = true
+			name = name[1:]
+		}
+
+		var err error
+		if k.col, err = column(name); err != nil {
+			return err
+		}
+		keys = append(keys, k)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, k := range keys {
+			c := compareValues(rows[i][k.col], rows[j][k.col])
+			if c == 0 {
+				continue
+			}
+			return (c < 0) != k.desc
+		}
+		return false
+	})
+	return nil
+}
+
+// compareValues compares two values, as numbers if both are
+func compareValues(a string, b string) int {
+	fa, aerr := strconv.ParseFloat(a, 64)
+	fb, berr := strconv.ParseFloat(b, 64)
+	switch {
+	case aerr != nil || berr != nil:
+		return strings.Compare(a, b)
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+// flatten joins the non-blank lines of a value
+func flatten(v string) string {
+	lines := []string{}
+	for _, l := range strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, flattenSeparator)
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package print
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// markdownEscaper escapes the characters which would otherwise format text, or end a table cell or link text
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;", ">", "&gt;")
+
+// MarkdownEscape makes text safe to use in a Markdown table cell or link text, collapsing it onto one line
+func MarkdownEscape(s string) string {
+	return markdownEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownTable returns a slice of structs, or of pointers to them, as a GitHub-flavored Markdown table with a
+// column per field. If the structs have a URL field, it links their Title in place of a column of its own.
+func markdownTable(data interface{}) (string, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return "", fmt.Errorf("markdown: %T is not a slice", data)
+	}
+
+	t := v.Type().Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("markdown: %T is not a slice of structs", data)
+	}
+
+	_, hasTitle := t.FieldByName("Title")
+	_, hasURL := t.FieldByName("URL")
+	link := hasTitle && hasURL
+
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || (link && f.Name == "URL") {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	var sb strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&sb, "| %s ", f.Name)
+	}
+	sb.WriteString("|\n")
+	for _, f := range fields {
+		if isNumber(f.Type.Kind()) {
+			sb.WriteString("|---:")
+		} else {
+			sb.WriteString("|---")
+		}
+	}
+	sb.WriteString("|\n")
+
+	for i := 0; i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		if !row.IsValid() {
+			continue
+		}
+		for _, f := range fields {
+			cell := markdownCell(row.FieldByIndex(f.Index))
+			if link && f.Name == "Title" {
+				cell = fmt.Sprintf("[%s](%s)", cell, row.FieldByName("URL").String())
+			}
+			fmt.Fprintf(&sb, "| %s ", cell)
+		}
+		sb.WriteString("|\n")
+	}
+
+	return sb.String(), nil
+}
+
+// markdownCell returns a value for a table cell. Lines of multi-line text, such as the files of a pull request, are
+// separated by line breaks.
+func markdownCell(v reflect.Value) string {
+	if v.Kind() != reflect.String {
+		return MarkdownEscape(fmt.Sprint(v.Interface()))
+	}
+
+	lines := []string{}
+	for _, l := range strings.Split(v.String(), "\n") {
+		if l = MarkdownEscape(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "<br>")
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// quotes returns the metadata as Markdown block quotes
+func (md *Metadata) quotes() string {
+	var sb strings.Builder
+	if md.Incomplete {
+		fmt.Fprintf(&sb, "> **Incomplete:** %s\n\n", MarkdownEscape(md.Reason))
+	}
+	for _, e := range md.Errors {
+		fmt.Fprintf(&sb, "> **Error:** %s for %s: %s\n\n", e.Kind, MarkdownEscape(e.Repo), MarkdownEscape(e.Error))
+	}
+	return sb.String()
+}
@@ -0,0 +1,413 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package print
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// Parquet records mirror the summaries, with dates and timestamps as such, and the files of a pull request as a
+// list. Timestamps are null if unknown.
+
+type prRecord struct {
+	URL            string   `parquet:"url"`
+	Date           int32    `parquet:"date,date"`
+	User           string   `parquet:"user,dict"`
+	Project        string   `parquet:"project,dict"`
+	Type           string   `parquet:"type,dict"`
+	Title          string   `parquet:"title"`
+	Delta          int64    `parquet:"delta"`
+	Added          int64    `parquet:"added"`
+	Deleted        int64    `parquet:"deleted"`
+	FilesTotal     int64    `parquet:"files_total"`
+	Files          []string `parquet:"files,list"`
+	Description    string   `parquet:"description"`
+	CreatedAt      int64    `parquet:"created_at,optional,timestamp(millisecond)"`
+	MergedAt       int64    `parquet:"merged_at,optional,timestamp(millisecond)"`
+	ClosedAt       int64    `parquet:"closed_at,optional,timestamp(millisecond)"`
+	LastActivityAt int64    `parquet:"last_activity_at,optional,timestamp(millisecond)"`
+}
+
+type reviewRecord struct {
+	URL            string `parquet:"url"`
+	Date           int32  `parquet:"date,date"`
+	Project        string `parquet:"project,dict"`
+	Reviewer       string `parquet:"reviewer,dict"`
+	PRAuthor       string `parquet:"pr_author,dict"`
+	PRComments     int64  `parquet:"pr_comments"`
+	ReviewComments int64  `parquet:"review_comments"`
+	Words          int64  `parquet:"words"`
+	Title          string `parquet:"title"`
+	CreatedAt      int64  `parquet:"created_at,optional,timestamp(millisecond)"`
+	MergedAt       int64  `parquet:"merged_at,optional,timestamp(millisecond)"`
+	LastActivityAt int64  `parquet:"last_activity_at,optional,timestamp(millisecond)"`
+}
+
+type issueRecord struct {
+	URL            string `parquet:"url"`
+	Date           int32  `parquet:"date,date"`
+	Author         string `parquet:"author,dict"`
+	Closer         string `parquet:"closer,dict"`
+	Project        string `parquet:"project,dict"`
+	Type           string `parquet:"type,dict"`
+	Title          string `parquet:"title"`
+	CreatedAt      int64  `parquet:"created_at,optional,timestamp(millisecond)"`
+	ClosedAt       int64  `parquet:"closed_at,optional,timestamp(millisecond)"`
+	LastActivityAt int64  `parquet:"last_activity_at,optional,timestamp(millisecond)"`
+}
+
+type commentRecord struct {
+	URL            string `parquet:"url"`
+	Date           int32  `parquet:"date,date"`
+	Project        string `parquet:"project,dict"`
+	Commenter      string `parquet:"commenter,dict"`
+	IssueAuthor    string `parquet:"issue_author,dict"`
+	IssueState     string `parquet:"issue_state,dict"`
+	Comments       int64  `parquet:"comments"`
+	Words          int64  `parquet:"words"`
+	Title          string `parquet:"title"`
+	CreatedAt      int64  `parquet:"created_at,optional,timestamp(millisecond)"`
+	ClosedAt       int64  `parquet:"closed_at,optional,timestamp(millisecond)"`
+	LastActivityAt int64  `parquet:"last_activity_at,optional,timestamp(millisecond)"`
+}
+
+// WriteParquet writes summaries, such as a []*repo.PRSummary, to w as a Parquet file
+func WriteParquet(w io.Writer, data interface{}) error {
+	switch v := data.(type) {
+	case []*repo.PRSummary:
+		rs, err := prRecords(v)
+		if err != nil {
+			return err
+		}
+		return parquet.Write(w, rs)
+	case []*repo.ReviewSummary:
+		rs, err := reviewRecords(v)
+		if err != nil {
+			return err
+		}
+		return parquet.Write(w, rs)
+	case []*repo.IssueSummary:
+		rs, err := issueRecords(v)
+		if err != nil {
+			return err
+		}
+		return parquet.Write(w, rs)
+	case []*repo.CommentSummary:
+		rs, err := commentRecords(v)
+		if err != nil {
+			return err
+		}
+		return parquet.Write(w, rs)
+	}
+	return fmt.Errorf("parquet: %T is not a slice of pull request, review, issue or comment summaries", data)
+}
+
+// WriteParquetDir writes summaries into dir as Parquet files partitioned by project and month, such as
+// prs/project=minikube/month=2021-03/data.parquet. Summaries are merged into the partitions they fall into:
+// rows already in any month of the project for the same pull request or issue (and reviewer or commenter) are
+// replaced, and other rows are kept. The paths of the files written are returned.
+func WriteParquetDir(dir string, data interface{}) ([]string, error) {
+	switch v := data.(type) {
+	case []*repo.PRSummary:
+		rs, err := prRecords(v)
+		if err != nil {
+			return nil, err
+		}
+		return writePartitions(filepath.Join(dir, "prs"), rs,
+			func(r prRecord) (string, int32) { return r.Project, r.Date },
+			func(r prRecord) string { return r.URL })
+	case []*repo.ReviewSummary:
+		rs, err := reviewRecords(v)
+		if err != nil {
+			return nil, err
+		}
+		return writePartitions(filepath.Join(dir, "reviews"), rs,
+			func(r reviewRecord) (string, int32) { return r.Project, r.Date },
+			func(r reviewRecord) string { return r.URL + " " + r.Reviewer })
+	case []*repo.IssueSummary:
+		rs, err := issueRecords(v)
+		if err != nil {
+			return nil, err
+		}
+		return writePartitions(filepath.Join(dir, "issues"), rs,
+			func(r issueRecord) (string, int32) { return r.Project, r.Date },
+			func(r issueRecord) string { return r.URL })
+	case []*repo.CommentSummary:
+		rs, err := commentRecords(v)
+		if err != nil {
+			return nil, err
+		}
+		return writePartitions(filepath.Join(dir, "comments"), rs,
+			func(r commentRecord) (string, int32) { return r.Project, r.Date },
+			func(r commentRecord) string { return r.URL + " " + r.Commenter })
+	}
+	return nil, fmt.Errorf("parquet: %T is not a slice of pull request, review, issue or comment summaries", data)
+}
+
+// writePartitions merges records into a file per project and month of their date. Existing rows with the same
+// id as a new record are replaced by it, in whichever month of the project they were, so that a row whose date has
+// moved to another month is not kept twice. Partitions left without rows are removed.
+func writePartitions[T any](dir string, records []T, key func(T) (string, int32), id func(T) string) ([]string, error) {
+	// project directory -> partition directory -> records
+	projects := map[string]map[string][]T{}
+	for _, r := range records {
+		project, date := key(r)
+		month := time.Unix(int64(date)*24*60*60, 0).UTC().Format("2006-01")
+		pd := filepath.Join(dir, "project="+partitionValue(project))
+		if projects[pd] == nil {
+			projects[pd] = map[string][]T{}
+		}
+		p := filepath.Join(pd, "month="+month)
+		projects[pd][p] = append(projects[pd][p], r)
+	}
+
+	pds := []string{}
+	for pd := range projects {
+		pds = append(pds, pd)
+	}
+	sort.Strings(pds)
+
+	files := []string{}
+	for _, pd := range pds {
+		parts := projects[pd]
+		replaced := map[string]bool{}
+		for _, rs := range parts {
+			for _, r := range rs {
+				replaced[id(r)] = true
+			}
+		}
+
+		existing, err := filepath.Glob(filepath.Join(pd, "month=*", "data.parquet"))
+		if err != nil {
+			return files, err
+		}
+		paths := []string{}
+		for _, path := range existing {
+			if _, ok := parts[filepath.Dir(path)]; !ok {
+				paths = append(paths, filepath.Dir(path))
+			}
+		}
+		for p := range parts {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		for _, p := range paths {
+			path := filepath.Join(p, "data.parquet")
+			rows, changed, err := mergeRows(path, parts[p], replaced, id)
+			if err != nil {
+				return files, err
+			}
+			if !changed {
+				continue
+			}
+
+			if len(rows) == 0 {
+				if err := os.Remove(path); err != nil {
+					return files, err
+				}
+				if err := os.Remove(p); err != nil {
+					klog.Warningf("unable to remove empty partition %s: %v", p, err)
+				}
+				continue
+			}
+
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				return files, err
+			}
+
+			// Written to a temporary file first, so that a failure never leaves a partition half written
+			tmp := path + ".tmp"
+			if err := parquet.WriteFile(tmp, rows); err != nil {
+				return files, fmt.Errorf("%s: %v", path, err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				return files, err
+			}
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// mergeRows returns the rows of an existing partition file whose ids are not replaced, followed by the records, and
+// whether that differs from what the file holds
+func mergeRows[T any](path string, records []T, replaced map[string]bool, id func(T) string) ([]T, bool, error) {
+	existing, err := parquet.ReadFile[T](path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, len(records) > 0, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", path, err)
+	}
+
+	rows := []T{}
+	for _, r := range existing {
+		if !replaced[id(r)] {
+			rows = append(rows, r)
+		}
+	}
+	changed := len(records) > 0 || len(rows) < len(existing)
+	return append(rows, records...), changed, nil
+}
+
+// partitionValue makes a value safe to use in a partition directory name
+func partitionValue(s string) string {
+	if s == "" {
+		return "__unknown__"
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", "=", "_").Replace(s)
+}
+
+func prRecords(sums []*repo.PRSummary) ([]prRecord, error) {
+	rs := []prRecord{}
+	for _, s := range sums {
+		date, err := parquetDate(s.URL, s.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []string{}
+		for _, f := range strings.Split(s.Files, "\n") {
+			if f != "" {
+				files = append(files, f)
+			}
+		}
+
+		rs = append(rs, prRecord{
+			URL:            s.URL,
+			Date:           date,
+			User:           s.User,
+			Project:        s.Project,
+			Type:           s.Type,
+			Title:          s.Title,
+			Delta:          int64(s.Delta),
+			Added:          int64(s.Added),
+			Deleted:        int64(s.Deleted),
+			FilesTotal:     int64(s.FilesTotal),
+			Files:          files,
+			Description:    s.Description,
+			CreatedAt:      parquetTimestamp(s.CreatedAt),
+			MergedAt:       parquetTimestamp(s.MergedAt),
+			ClosedAt:       parquetTimestamp(s.ClosedAt),
+			LastActivityAt: parquetTimestamp(s.LastActivityAt),
+		})
+	}
+	return rs, nil
+}
+
+func reviewRecords(sums []*repo.ReviewSummary) ([]reviewRecord, error) {
+	rs := []reviewRecord{}
+	for _, s := range sums {
+		date, err := parquetDate(s.URL, s.Date)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, reviewRecord{
+			URL:            s.URL,
+			Date:           date,
+			Project:        s.Project,
+			Reviewer:       s.Reviewer,
+			PRAuthor:       s.PRAuthor,
+			PRComments:     int64(s.PRComments),
+			ReviewComments: int64(s.ReviewComments),
+			Words:          int64(s.Words),
+			Title:          s.Title,
+			CreatedAt:      parquetTimestamp(s.CreatedAt),
+			MergedAt:       parquetTimestamp(s.MergedAt),
+			LastActivityAt: parquetTimestamp(s.LastActivityAt),
+		})
+	}
+	return rs, nil
+}
+
+func issueRecords(sums []*repo.IssueSummary) ([]issueRecord, error) {
+	rs := []issueRecord{}
+	for _, s := range sums {
+		date, err := parquetDate(s.URL, s.Date)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, issueRecord{
+			URL:            s.URL,
+			Date:           date,
+			Author:         s.Author,
+			Closer:         s.Closer,
+			Project:        s.Project,
+			Type:           s.Type,
+			Title:          s.Title,
+			CreatedAt:      parquetTimestamp(s.CreatedAt),
+			ClosedAt:       parquetTimestamp(s.ClosedAt),
+			LastActivityAt: parquetTimestamp(s.LastActivityAt),
+		})
+	}
+	return rs, nil
+}
+
+func commentRecords(sums []*repo.CommentSummary) ([]commentRecord, error) {
+	rs := []commentRecord{}
+	for _, s := range sums {
+		date, err := parquetDate(s.URL, s.Date)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, commentRecord{
+			URL:            s.URL,
+			Date:           date,
+			Project:        s.Project,
+			Commenter:      s.Commenter,
+			IssueAuthor:    s.IssueAuthor,
+			IssueState:     s.IssueState,
+			Comments:       int64(s.Comments),
+			Words:          int64(s.Words),
+			Title:          s.Title,
+			CreatedAt:      parquetTimestamp(s.CreatedAt),
+			ClosedAt:       parquetTimestamp(s.ClosedAt),
+			LastActivityAt: parquetTimestamp(s.LastActivityAt),
+		})
+	}
+	return rs, nil
+}
+
+// parquetDate returns the date of a summary as days since the Unix epoch, as Parquet stores dates
+func parquetDate(url string, s string) (int32, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, fmt.Errorf("parquet: %s has an invalid date %q: %v", url, s, err)
+	}
+	return int32(t.Unix() / (24 * 60 * 60)), nil
+}
+
+// parquetTimestamp returns an RFC 3339 timestamp as milliseconds since the Unix epoch, or 0 (null) if it is unknown
+func parquetTimestamp(s string) int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
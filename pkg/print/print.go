@@ -17,32 +17,137 @@ package print
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/gocarina/gocsv"
 	"k8s.io/klog/v2"
 )
 
-// Print the values in "data" interface to standatrd output in the format specified by "out_type", either JSON/CSV
-func Print(data interface{}, outType string) error {
+// Metadata describes output as a whole. It is only included in output when set.
+type Metadata struct {
+	Incomplete bool        // Whether the run stopped before collecting everything
+	Reason     string      `json:",omitempty"` // Why the run stopped early
+	Errors     []RepoError `json:",omitempty"` // Repositories whose data is missing, if the run kept going
+}
+
+// RepoError describes a failure to collect one kind of data for a repository
+type RepoError struct {
+	Repo  string
+	Kind  string // prs, reviews, issues or comments
+	Error string
+}
+
+// Formats are the output types supported by Print
+var Formats = []string{"CSV", "JSON", "NDJSON", "Markdown", "XLSX", "Parquet"}
+
+// Options customize output. The zero value prints records alone, in the default layout.
+type Options struct {
+	// Metadata is included in output when set. JSON output is then an object holding Metadata and Results, Markdown
+	// output is preceded by block quotes, and XLSX output by a Notes sheet. CSV, NDJSON and Parquet output hold only
+	// records, so metadata is logged instead, except that incomplete CSV output starts with a "# incomplete" line.
+	// With CSV comments, repository errors are listed in "#" lines too.
+	Metadata *Metadata
+	CSV      CSVOptions
+}
+
+// Print the values in "data" interface to standard output in the format specified by "out_type", either JSON/NDJSON/CSV/Markdown/XLSX/Parquet
+func Print(data interface{}, outType string, opts Options) error {
+	out, err := Marshal(data, outType, opts)
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("%d bytes of %s output", len(out), outType)
+	fmt.Print(out)
+
+	return nil
+}
+
+// Marshal returns the values in "data" interface in the format specified by "out_type", as Print prints them
+func Marshal(data interface{}, outType string, opts Options) (string, error) {
 	var (
 		err error
 		out string
+		md  = opts.Metadata
 	)
 
-	if outType == "JSON" {
+	switch strings.ToUpper(outType) {
+	case "JSON":
+		var v interface{} = data
+		if md != nil {
+			v = struct {
+				Metadata *Metadata
+				Results  interface{}
+			}{md, data}
+		}
+
 		var jsonvar []byte
-		jsonvar, err = json.Marshal(data)
+		jsonvar, err = json.Marshal(v)
 		out = string(jsonvar)
-	} else if outType == "CSV" {
+	case "NDJSON":
+		var sb strings.Builder
+		err = WriteNDJSON(&sb, data)
+		out = sb.String()
+		if md != nil {
+			md.Log()
+		}
+	case "PARQUET":
+		var sb strings.Builder
+		err = WriteParquet(&sb, data)
+		out = sb.String()
+		if md != nil {
+			md.Log()
+		}
+	case "CSV":
 		out, err = gocsv.MarshalString(data)
+		if err == nil && opts.CSV.rewrites() {
+			out, err = formatCSV(out, opts.CSV)
+		}
+		if md != nil {
+			out = md.comments(opts.CSV.Comments) + out
+		}
+		if md != nil && !opts.CSV.Comments {
+			md.Log()
+		}
+		if opts.CSV.BOM {
+			out = "\ufeff" + out
+		}
+	case "MARKDOWN":
+		out, err = markdownTable(data)
+		if md != nil {
+			out = md.quotes() + out
+		}
+	case "XLSX":
+		out, err = MarshalXLSX([]Sheet{{Name: sheetName(data), Data: data}}, md)
+	default:
+		err = fmt.Errorf("unknown output type %q", outType)
 	}
 
-	if err != nil {
-		return err
-	}
+	return out, err
+}
 
-	klog.Infof("%d bytes of reviews output", len(out))
-	fmt.Print(out)
+// Log logs the metadata, for output which cannot hold it, such as NDJSON
+func (md *Metadata) Log() {
+	if md.Incomplete {
+		klog.Warningf("results are incomplete: %s", md.Reason)
+	}
+	for _, e := range md.Errors {
+		klog.Warningf("results are missing %s for %s: %s", e.Kind, e.Repo, e.Error)
+	}
+}
 
-	return nil
+// comments returns the metadata as CSV comment lines: whether the output is incomplete, and the repository errors
+// if asked for. Readers of partial output can always tell it is partial.
+func (md *Metadata) comments(withErrors bool) string {
+	var sb strings.Builder
+	if md.Incomplete {
+		fmt.Fprintf(&sb, "# incomplete: %s\n", strings.ReplaceAll(md.Reason, "\n", " "))
+	}
+	if !withErrors {
+		return sb.String()
+	}
+	for _, e := range md.Errors {
+		fmt.Fprintf(&sb, "# error: %s for %s: %s\n", e.Kind, e.Repo, strings.ReplaceAll(e.Error, "\n", " "))
+	}
+	return sb.String()
 }
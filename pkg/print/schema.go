@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package print
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns the JSON Schema of a record of JSON or NDJSON output, such as a repo.PRSummary. Date and Since
+// fields are dates, and fields ending in "At" are RFC 3339 timestamps, or empty if unknown.
+func JSONSchema(record interface{}, title string) (map[string]interface{}, error) {
+	t := reflect.TypeOf(record)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %T is not a struct", record)
+	}
+
+	props := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		p, err := fieldSchema(f)
+		if err != nil {
+			return nil, err
+		}
+		props[f.Name] = p
+		required = append(required, f.Name)
+	}
+
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                title,
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+// fieldSchema returns the JSON Schema of a field
+func fieldSchema(f reflect.StructField) (map[string]interface{}, error) {
+	switch f.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.String:
+	default:
+		return nil, fmt.Errorf("schema: %s has unsupported type %s", f.Name, f.Type)
+	}
+
+	switch {
+	case f.Name == "Date" || f.Name == "Since":
+		return map[string]interface{}{"type": "string", "format": "date"}, nil
+	case strings.HasSuffix(f.Name, "At"):
+		return map[string]interface{}{
+			"type":  "string",
+			"anyOf": []interface{}{map[string]interface{}{"format": "date-time"}, map[string]interface{}{"maxLength": 0}},
+		}, nil
+	}
+	return map[string]interface{}{"type": "string"}, nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package print
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Sheet is a worksheet of a workbook, holding a slice of structs, or of pointers to them, with a column per field
+type Sheet struct {
+	Name string
+	Data interface{}
+}
+
+// xlsxStyles are the cell styles of a workbook
+type xlsxStyles struct {
+	header, date, timestamp, link int
+}
+
+// MarshalXLSX returns an XLSX workbook with a sheet for each data set. Integer fields are numeric columns, Date and
+// Since fields are date columns, fields ending in "At" are date and time columns, and URL fields are hyperlinks.
+// Headers are frozen and filterable. If metadata is set, it is listed on a Notes sheet first.
+func MarshalXLSX(sheets []Sheet, md *Metadata) (string, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	st, err := newXLSXStyles(f)
+	if err != nil {
+		return "", err
+	}
+
+	if md != nil {
+		sheets = append([]Sheet{{Name: "Notes", Data: md.notes()}}, sheets...)
+	}
+
+	for i, s := range sheets {
+		if i == 0 {
+			err = f.SetSheetName(f.GetSheetName(0), s.Name)
+		} else {
+			_, err = f.NewSheet(s.Name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("sheet %s: %v", s.Name, err)
+		}
+		if err := writeSheet(f, st, s); err != nil {
+			return "", fmt.Errorf("sheet %s: %v", s.Name, err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// newXLSXStyles adds the cell styles used by sheets to a workbook
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	st := &xlsxStyles{}
+	dateFmt := "yyyy-mm-dd"
+	timestampFmt := "yyyy-mm-dd hh:mm:ss"
+
+	var err error
+	if st.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return nil, err
+	}
+	if st.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFmt}); err != nil {
+		return nil, err
+	}
+	if st.timestamp, err = f.NewStyle(&excelize.Style{CustomNumFmt: &timestampFmt}); err != nil {
+		return nil, err
+	}
+	if st.link, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "0563C1", Underline: "single"}}); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// writeSheet writes the data of a sheet, which must already exist in the workbook
+func writeSheet(f *excelize.File, st *xlsxStyles, s Sheet) error {
+	v := reflect.ValueOf(s.Data)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("%T is not a slice", s.Data)
+	}
+
+	t := v.Type().Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("%T is not a slice of structs", s.Data)
+	}
+
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			fields = append(fields, t.Field(i))
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("%s has no exported fields", t)
+	}
+
+	for c, fd := range fields {
+		cell, err := excelize.CoordinatesToCellName(c+1, 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellValue(s.Name, cell, fd.Name); err != nil {
+			return err
+		}
+	}
+	last, err := excelize.CoordinatesToCellName(len(fields), 1)
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(s.Name, "A1", last, st.header); err != nil {
+		return err
+	}
+
+	row := 1
+	for i := 0; i < v.Len(); i++ {
+		rv := reflect.Indirect(v.Index(i))
+		if !rv.IsValid() {
+			continue
+		}
+		row++
+
+		for c, fd := range fields {
+			cell, err := excelize.CoordinatesToCellName(c+1, row)
+			if err != nil {
+				return err
+			}
+			if err := setCell(f, st, s.Name, cell, fd.Name, rv.FieldByIndex(fd.Index)); err != nil {
+				return fmt.Errorf("%s: %v", cell, err)
+			}
+		}
+	}
+
+	if err := f.SetPanes(s.Name, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	last, err = excelize.CoordinatesToCellName(len(fields), row)
+	if err != nil {
+		return err
+	}
+	return f.AutoFilter(s.Name, "A1:"+last, nil)
+}
+
+// setCell sets a cell to the value of a field, typed by the field's name and kind
+func setCell(f *excelize.File, st *xlsxStyles, sheet string, cell string, name string, v reflect.Value) error {
+	if v.Kind() != reflect.String {
+		return f.SetCellValue(sheet, cell, v.Interface())
+	}
+
+	s := v.String()
+	switch {
+	case s == "":
+		return nil
+	case name == "URL":
+		if err := f.SetCellValue(sheet, cell, s); err != nil {
+			return err
+		}
+		if err := f.SetCellHyperLink(sheet, cell, s, "External"); err != nil {
+			return err
+		}
+		return f.SetCellStyle(sheet, cell, cell, st.link)
+	case name == "Date" || name == "Since":
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return setTime(f, sheet, cell, t, st.date)
+		}
+	case strings.HasSuffix(name, "At"):
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return setTime(f, sheet, cell, t, st.timestamp)
+		}
+	}
+	return f.SetCellValue(sheet, cell, s)
+}
+
+// setTime sets a cell to a time as it reads on the clock of its zone, as spreadsheets have no time zones
+func setTime(f *excelize.File, sheet string, cell string, t time.Time, style int) error {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if err := f.SetCellValue(sheet, cell, wall); err != nil {
+		return err
+	}
+	return f.SetCellStyle(sheet, cell, cell, style)
+}
+
+// note is a row of the Notes sheet
+type note struct {
+	Kind    string
+	Repo    string
+	Message string
+}
+
+// notes returns the metadata as rows of a Notes sheet
+func (md *Metadata) notes() []note {
+	ns := []note{}
+	if md.Incomplete {
+		ns = append(ns, note{Kind: "incomplete", Message: md.Reason})
+	}
+	for _, e := range md.Errors {
+		ns = append(ns, note{Kind: "error: " + e.Kind, Repo: e.Repo, Message: e.Error})
+	}
+	return ns
+}
+
+// sheetName returns the name of the sheet for a slice of summaries, such as PRs for a []*repo.PRSummary
+func sheetName(data interface{}) string {
+	t := reflect.TypeOf(data)
+	if t == nil || t.Kind() != reflect.Slice {
+		return "Sheet1"
+	}
+
+	t = t.Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Summary") + "s"
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pullsheet generates reports about GitHub contributions, for use from other Go programs.
+//
+// A Report holds every setting explicitly, so several reports with different settings can be
+// generated in one process, sharing a client:
+//
+//	c, err := client.New(ctx, client.Config{GitHubToken: token})
+//	...
+//	r := &pullsheet.Report{Repos: []string{"google/pullsheet"}, Since: since, Until: until}
+//	html, err := r.Leaderboard(ctx, c)
+package pullsheet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/leaderboard"
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/repo"
+	"github.com/google/pullsheet/pkg/summary"
+)
+
+// Report describes a report of GitHub activity
+type Report struct {
+	Org         string         // If set, every repository in the org is reported on in place of Repos
+	Repos       []string       // Repositories to report on, as org/project or URLs
+	Users       []string       // Users to report on. Defaults to everyone
+	Branches    []string       // Branches that merged pull requests must target. Defaults to any
+	Since       time.Time      // Start of the window to report on
+	Until       time.Time      // End of the window to report on
+	IncludeBots bool           // Whether activity by bots is reported
+	Location    *time.Location // Time zone of dates and timestamps in summaries. Defaults to UTC
+	Title       string         // Title of the leaderboard. Defaults to the repositories
+	Command     string         // Command line shown on the leaderboard, if any
+}
+
+// Data is the activity gathered for a report
+type Data struct {
+	PRs      []*repo.PRSummary
+	Reviews  []*repo.ReviewSummary
+	Issues   []*repo.IssueSummary
+	Comments []*repo.CommentSummary
+}
+
+// Pulls returns summaries of the pull requests merged in the report window
+func (r *Report) Pulls(ctx context.Context, c *client.Client) ([]*repo.PRSummary, error) {
+	c, repos, err := r.setup(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Pulls(ctx, c, repos, r.Users, r.Branches, r.Since, r.Until)
+}
+
+// Reviews returns summaries of the reviews of pull requests merged in the report window
+func (r *Report) Reviews(ctx context.Context, c *client.Client) ([]*repo.ReviewSummary, error) {
+	c, repos, err := r.setup(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Reviews(ctx, c, repos, r.Users, r.Since, r.Until)
+}
+
+// Issues returns summaries of the issues closed in the report window
+func (r *Report) Issues(ctx context.Context, c *client.Client) ([]*repo.IssueSummary, error) {
+	c, repos, err := r.setup(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Issues(ctx, c, repos, r.Users, r.Since, r.Until)
+}
+
+// Comments returns summaries of the issue comments made in the report window
+func (r *Report) Comments(ctx context.Context, c *client.Client) ([]*repo.CommentSummary, error) {
+	c, repos, err := r.setup(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Comments(ctx, c, repos, r.Users, r.Since, r.Until)
+}
+
+// Data returns all of the activity for the report. On error, the activity gathered so far is returned along with it.
+func (r *Report) Data(ctx context.Context, c *client.Client) (*Data, error) {
+	d := &Data{}
+	c, repos, err := r.setup(ctx, c)
+	if err != nil {
+		return d, err
+	}
+
+	// Resolve the org once, rather than in each of the summary calls
+	rr := *r
+	rr.Org = ""
+	rr.Repos = repos
+
+	if d.PRs, err = rr.Pulls(ctx, c); err != nil {
+		return d, fmt.Errorf("pulls: %w", err)
+	}
+
+	if d.Reviews, err = rr.Reviews(ctx, c); err != nil {
+		return d, fmt.Errorf("reviews: %w", err)
+	}
+
+	if d.Issues, err = rr.Issues(ctx, c); err != nil {
+		return d, fmt.Errorf("issues: %w", err)
+	}
+
+	if d.Comments, err = rr.Comments(ctx, c); err != nil {
+		return d, fmt.Errorf("comments: %w", err)
+	}
+
+	return d, nil
+}
+
+// Leaderboard returns the report as an HTML leaderboard page
+func (r *Report) Leaderboard(ctx context.Context, c *client.Client) (string, error) {
+	d, err := r.Data(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	return r.Render(d)
+}
+
+// Render returns previously gathered data as an HTML leaderboard page
+func (r *Report) Render(d *Data) (string, error) {
+	return r.RenderWithWarnings(d, nil)
+}
+
+// RenderWithWarnings renders like Render, showing warnings in a banner, such as when the data is incomplete
+func (r *Report) RenderWithWarnings(d *Data, warnings []string) (string, error) {
+	return leaderboard.Render(leaderboard.Options{
+		Title:    r.DisplayTitle(),
+		Since:    r.Since,
+		Until:    r.Until,
+		Command:  r.Command,
+		Warnings: warnings,
+	}, r.Users, d.PRs, d.Reviews, d.Issues, d.Comments)
+}
+
+// DisplayTitle returns the title shown on the leaderboard: Title, or else the org or repositories
+func (r *Report) DisplayTitle() string {
+	if r.Title != "" {
+		return r.Title
+	}
+	if r.Org != "" {
+		return r.Org
+	}
+	return strings.Join(r.Repos, ", ")
+}
+
+// Format returns summaries, such as those returned by Pulls, in the given format: CSV, JSON or Markdown
+func Format(summaries interface{}, format string) (string, error) {
+	return print.Marshal(summaries, strings.ToUpper(format), print.Options{})
+}
+
+// setup returns a client applying the report's bot policy, and the repositories to report on
+func (r *Report) setup(ctx context.Context, c *client.Client) (*client.Client, []string, error) {
+	rc := *c
+	rc.IncludeBots = r.IncludeBots
+	rc.Location = r.Location
+
+	if r.Org == "" {
+		return &rc, r.Repos, nil
+	}
+
+	repos, err := repo.ListRepoNames(ctx, &rc, r.Org)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list repos for %s: %w", r.Org, err)
+	}
+	return &rc, repos, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PageCheckpoint records how far a paged listing has progressed, so that an interrupted listing can be resumed
+type PageCheckpoint interface {
+	// ResumePage returns the next page to list (0 if the listing finished) and the numbers of the items found
+	// on earlier pages, if progress for key was saved.
+	ResumePage(key string) (next int, found []int, ok bool)
+	// SavePage records the next page to list (0 if the listing finished) and the numbers of the items found so far.
+	SavePage(key string, next int, found []int) error
+	// ResumeCursor is ResumePage for listings paged by cursor, such as GraphQL connections. The cursor is "" if the
+	// listing finished.
+	ResumeCursor(key string) (next string, found []int, ok bool)
+	// SaveCursor is SavePage for listings paged by cursor. The cursor is "" if the listing finished.
+	SaveCursor(key string, next string, found []int) error
+}
+
+type pageCheckpointKey struct{}
+
+// WithPageCheckpoint returns a context in which paged listings record their progress to cp, resuming from it where possible
+func WithPageCheckpoint(ctx context.Context, cp PageCheckpoint) context.Context {
+	return context.WithValue(ctx, pageCheckpointKey{}, cp)
+}
+
+// pageCheckpoint returns the page checkpoint for a context, if any
+func pageCheckpoint(ctx context.Context) PageCheckpoint {
+	cp, _ := ctx.Value(pageCheckpointKey{}).(PageCheckpoint)
+	return cp
+}
+
+// listingKey identifies a paged listing by everything that affects which items it finds
+func listingKey(kind string, org string, project string, updated time.Time, filters ...[]string) string {
+	parts := []string{kind, org, project, fmt.Sprint(updated.Unix())}
+	for _, f := range filters {
+		parts = append(parts, strings.ToLower(strings.Join(f, ",")))
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,348 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/ghcache"
+)
+
+// mergedPullsQuery fetches a page of merged pull requests along with the data the REST path needs per PR
+const mergedPullsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(states: MERGED, orderBy: {field: UPDATED_AT, direction: DESC}, first: 25, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number title body url merged mergedAt closedAt createdAt updatedAt
+        changedFiles additions deletions baseRefName
+        mergeCommit { oid }
+        author { login __typename }
+        files(first: 100) {
+          pageInfo { hasNextPage }
+          nodes { path additions deletions changeType }
+        }
+        comments(first: 100) {
+          pageInfo { hasNextPage }
+          nodes { url body createdAt author { login __typename } }
+        }
+        reviews(first: 50) {
+          pageInfo { hasNextPage }
+          nodes {
+            state
+            comments(first: 50) {
+              pageInfo { hasNextPage }
+              nodes { url body createdAt author { login __typename } }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type gqlPageInfo struct {
+	HasNextPage bool
+	EndCursor   string
+}
+
+type gqlActor struct {
+	Login    string
+	Typename string `json:"__typename"`
+}
+
+type gqlComment struct {
+	URL       string
+	Body      string
+	CreatedAt time.Time
+	Author    *gqlActor
+}
+
+type gqlComments struct {
+	PageInfo gqlPageInfo
+	Nodes    []gqlComment
+}
+
+type gqlPullRequest struct {
+	Number       int
+	Title        string
+	Body         string
+	URL          string
+	Merged       bool
+	MergedAt     time.Time
+	ClosedAt     time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ChangedFiles int
+	Additions    int
+	Deletions    int
+	BaseRefName  string
+	MergeCommit  *struct{ OID string }
+	Author       *gqlActor
+	Files        struct {
+		PageInfo gqlPageInfo
+		Nodes    []struct {
+			Path       string
+			Additions  int
+			Deletions  int
+			ChangeType string
+		}
+	}
+	Comments gqlComments
+	Reviews  struct {
+		PageInfo gqlPageInfo
+		Nodes    []struct {
+			State    string
+			Comments gqlComments
+		}
+	}
+}
+
+// mergedPullsGraphQL returns a list of merged pull requests in a project, using the GraphQL API.
+// Files and comments that arrive complete are stored in the cache, so that the REST code paths
+// which consume them are served without further API calls.
+func mergedPullsGraphQL(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string, branches []string) ([]*github.PullRequest, error) {
+	var result []*github.PullRequest
+
+	matchUser := map[string]bool{}
+	for _, u := range users {
+		matchUser[strings.ToLower(u)] = true
+	}
+
+	matchBranch := branchMatcher(branches)
+
+	vars := map[string]interface{}{"owner": org, "name": project, "cursor": nil}
+
+	cp := pageCheckpoint(ctx)
+	key := listingKey("graphql-pulls", org, project, updated, users, branches)
+	if cp != nil {
+		cursor, found, ok := cp.ResumeCursor(key)
+		if ok {
+			klog.Infof("Resuming %s/%s pull requests via GraphQL with %d found", org, project, len(found))
+			for _, num := range found {
+				pr := ghcache.GraphQLPullRequestsGet(c.Cache, time.Time{}, org, project, num)
+				if pr == nil {
+					var err error
+					if pr, err = pullRequestsGet(ctx, c, time.Time{}, org, project, num); err != nil {
+						return result, err
+					}
+				}
+				result = append(result, pr)
+			}
+			if cursor == "" {
+				return result, nil
+			}
+			vars["cursor"] = cursor
+		}
+	}
+
+	klog.Infof("Gathering pull requests for %s/%s via GraphQL, users=%q", org, project, users)
+	for page := 1; ; page++ {
+		var data struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo gqlPageInfo
+					Nodes    []gqlPullRequest
+				}
+			}
+		}
+		if err := c.GraphQL(ctx, mergedPullsQuery, vars, &data); err != nil {
+			return result, err
+		}
+
+		prs := data.Repository.PullRequests
+		klog.Infof("Processing page %d of %s/%s pull request results (looking for %s)...", page, org, project, updated)
+
+		done := !prs.PageInfo.HasNextPage
+		for i := range prs.Nodes {
+			n := &prs.Nodes[i]
+			if n.ClosedAt.After(until) {
+				continue
+			}
+
+			if n.UpdatedAt.Before(updated) {
+				klog.Infof("Hit PR#%d updated at %s", n.Number, n.UpdatedAt)
+				done = true
+				break
+			}
+
+			pr := n.pullRequest()
+			if len(matchUser) > 0 && !matchUser[strings.ToLower(pr.GetUser().GetLogin())] {
+				continue
+			}
+
+			if isBot(c, pr.GetUser()) {
+				continue
+			}
+
+			if !mergedInto(pr, since, matchBranch) {
+				continue
+			}
+
+			if err := n.store(c, org, project); err != nil {
+				return result, err
+			}
+
+			result = append(result, pr)
+		}
+
+		next := prs.PageInfo.EndCursor
+		if done {
+			next = ""
+		}
+		if cp != nil {
+			found := []int{}
+			for _, pr := range result {
+				found = append(found, pr.GetNumber())
+			}
+			if err := cp.SaveCursor(key, next, found); err != nil {
+				return result, err
+			}
+		}
+
+		if done {
+			break
+		}
+		vars["cursor"] = next
+	}
+
+	klog.Infof("Returning %d pull request results", len(result))
+	return result, nil
+}
+
+// store caches the pull request, along with any file and comment lists that were returned in full. The pull request
+// lacks most REST fields, so it is kept apart from those fetched by REST.
+func (n *gqlPullRequest) store(c *client.Client, org string, project string) error {
+	if err := ghcache.GraphQLPullRequestsSet(c.Cache, org, project, n.pullRequest()); err != nil {
+		return err
+	}
+
+	if !n.Files.PageInfo.HasNextPage {
+		fs := []*github.CommitFile{}
+		for _, f := range n.Files.Nodes {
+			fs = append(fs, &github.CommitFile{
+				Filename:  github.String(f.Path),
+				Additions: github.Int(f.Additions),
+				Deletions: github.Int(f.Deletions),
+				Changes:   github.Int(f.Additions + f.Deletions),
+				Status:    github.String(fileStatus(f.ChangeType)),
+			})
+		}
+		if err := ghcache.PullRequestsSetFiles(c.Cache, org, project, n.Number, fs); err != nil {
+			return err
+		}
+	}
+
+	if !n.Comments.PageInfo.HasNextPage {
+		cs := []*github.IssueComment{}
+		for _, gc := range n.Comments.Nodes {
+			cs = append(cs, &github.IssueComment{
+				HTMLURL:   github.String(gc.URL),
+				Body:      github.String(gc.Body),
+				CreatedAt: timePtr(gc.CreatedAt),
+				User:      gc.Author.user(),
+			})
+		}
+		if err := ghcache.IssuesSetComments(c.Cache, org, project, n.Number, cs); err != nil {
+			return err
+		}
+	}
+
+	// Every review comment belongs to a review. Pending reviews are only visible to their author, and are not
+	// listed by REST.
+	complete := !n.Reviews.PageInfo.HasNextPage
+	cs := []*github.PullRequestComment{}
+	for _, r := range n.Reviews.Nodes {
+		if r.State == "PENDING" {
+			continue
+		}
+		if r.Comments.PageInfo.HasNextPage {
+			complete = false
+		}
+		for _, gc := range r.Comments.Nodes {
+			cs = append(cs, &github.PullRequestComment{
+				HTMLURL:   github.String(gc.URL),
+				Body:      github.String(gc.Body),
+				CreatedAt: timePtr(gc.CreatedAt),
+				User:      gc.Author.user(),
+			})
+		}
+	}
+	if complete {
+		return ghcache.PullRequestsSetComments(c.Cache, org, project, n.Number, cs)
+	}
+
+	return nil
+}
+
+// pullRequest converts a GraphQL pull request into its REST representation
+func (n *gqlPullRequest) pullRequest() *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:       github.Int(n.Number),
+		Title:        github.String(n.Title),
+		Body:         github.String(n.Body),
+		HTMLURL:      github.String(n.URL),
+		State:        github.String("closed"),
+		Merged:       github.Bool(n.Merged),
+		MergedAt:     timePtr(n.MergedAt),
+		ClosedAt:     timePtr(n.ClosedAt),
+		CreatedAt:    timePtr(n.CreatedAt),
+		UpdatedAt:    timePtr(n.UpdatedAt),
+		ChangedFiles: github.Int(n.ChangedFiles),
+		Additions:    github.Int(n.Additions),
+		Deletions:    github.Int(n.Deletions),
+		Base:         &github.PullRequestBranch{Ref: github.String(n.BaseRefName)},
+		User:         n.Author.user(),
+	}
+
+	if n.MergeCommit != nil {
+		pr.MergeCommitSHA = github.String(n.MergeCommit.OID)
+	}
+
+	return pr
+}
+
+// user converts a GraphQL actor into a REST user. Deleted accounts are reported as "ghost", and bots get the "[bot]"
+// suffix GraphQL leaves off their logins, as in REST.
+func (a *gqlActor) user() *github.User {
+	if a == nil {
+		return &github.User{Login: github.String("ghost"), Type: github.String("User")}
+	}
+	if a.Typename == "Bot" {
+		return &github.User{Login: github.String(a.Login + "[bot]"), Type: github.String("Bot")}
+	}
+	return &github.User{Login: github.String(a.Login), Type: github.String(a.Typename)}
+}
+
+// fileStatus converts a GraphQL PatchStatus into a REST file status
+func fileStatus(changeType string) string {
+	if changeType == "DELETED" {
+		return "removed"
+	}
+	return strings.ToLower(changeType)
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -16,6 +16,7 @@ package repo
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -35,33 +36,48 @@ type IssueSummary struct {
 	Project string
 	Type    string
 	Title   string
+	// RFC 3339 timestamps
+	CreatedAt      string
+	ClosedAt       string
+	LastActivityAt string
 }
 
 // ClosedIssues returns a list of closed issues within a project
 func ClosedIssues(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, users []string) ([]*IssueSummary, error) {
-	closed, err := issues(ctx, c, org, project, since, until, users, "closed")
-	if err != nil {
-		return nil, err
-	}
+	result, _, err := ClosedIssuesUpdated(ctx, c, org, project, since, until, since, users)
+	return result, err
+}
+
+// ClosedIssuesUpdated returns a list of closed issues within a project, considering only issues updated at or
+// after updated. The URLs of all issues considered, closed or not, are also returned. On error, the issues found
+// so far are returned along with it.
+func ClosedIssuesUpdated(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string) ([]*IssueSummary, []string, error) {
+	closed, touched, err := issues(ctx, c, org, project, since, until, updated, users, "closed")
 
 	result := make([]*IssueSummary, 0, len(closed))
 	for _, i := range closed {
 		result = append(result, &IssueSummary{
 			URL:     i.GetHTMLURL(),
-			Date:    i.GetClosedAt().Format(dateForm),
+			Date:    Date(i.GetClosedAt(), c.Location),
 			Author:  i.GetUser().GetLogin(),
 			Closer:  i.GetClosedBy().GetLogin(),
 			Project: project,
 			Title:   i.GetTitle(),
+
+			CreatedAt:      Timestamp(i.GetCreatedAt(), c.Location),
+			ClosedAt:       Timestamp(i.GetClosedAt(), c.Location),
+			LastActivityAt: Timestamp(i.GetUpdatedAt(), c.Location),
 		})
 	}
 
-	return result, nil
+	return result, touched, err
 }
 
-// issues returns a list of issues in a project
-func issues(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, users []string, state string) ([]*github.Issue, error) {
+// issues returns a list of issues in a project updated at or after updated, along with the URLs of every issue
+// that was considered before filtering by state and user
+func issues(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string, state string) ([]*github.Issue, []string, error) {
 	result := []*github.Issue{}
+	touched := []string{}
 	opts := &github.IssueListByRepoOptions{
 		State:     state,
 		Sort:      "updated",
@@ -76,12 +92,31 @@ func issues(ctx context.Context, c *client.Client, org string, project string, s
 		matchUser[strings.ToLower(u)] = true
 	}
 
+	cp := pageCheckpoint(ctx)
+	key := listingKey("issues-"+state, org, project, updated, users)
+	start := 1
+	if cp != nil {
+		next, found, ok := cp.ResumePage(key)
+		if ok {
+			klog.Infof("Resuming %s/%s issues at page %d with %d found", org, project, next, len(found))
+			for _, num := range found {
+				i, err := ghcache.IssuesGet(ctx, c.Cache, c.GitHubClient, time.Time{}, org, project, num)
+				if err != nil {
+					return result, touched, err
+				}
+				result = append(result, i)
+				touched = append(touched, i.GetHTMLURL())
+			}
+			start = next
+		}
+	}
+
 	klog.Infof("Gathering issues for %s/%s, users=%q: %+v", org, project, users, opts)
-	for page := 1; page != 0; {
+	for page := start; page != 0; {
 		opts.ListOptions.Page = page
 		issues, resp, err := c.GitHubClient.Issues.ListByRepo(ctx, org, project, opts)
 		if err != nil {
-			return result, err
+			return result, touched, err
 		}
 		if len(issues) == 0 {
 			klog.Infof("There isn't any issue in %s/%s since %s", org, project, since)
@@ -102,7 +137,7 @@ func issues(ctx context.Context, c *client.Client, org string, project string, s
 				continue
 			}
 
-			if i.GetUpdatedAt().Before(since) {
+			if i.GetUpdatedAt().Before(updated) {
 				klog.Infof("Hit issue #%d updated at %s", i.GetNumber(), i.GetUpdatedAt())
 				page = 0
 				break
@@ -112,12 +147,14 @@ func issues(ctx context.Context, c *client.Client, org string, project string, s
 				continue
 			}
 
+			touched = append(touched, i.GetHTMLURL())
+
 			if state != "" && i.GetState() != state {
 				klog.Infof("Skipping issue #%d (state=%q)", i.GetNumber(), i.GetState())
 				continue
 			}
 
-			t := issueDate(i)
+			t := i.GetUpdatedAt()
 
 			klog.Infof("Fetching #%d (closed %s, updated %s): %q", i.GetNumber(), i.GetClosedAt().Format(dateForm), i.GetUpdatedAt().Format(dateForm), i.GetTitle())
 
@@ -127,8 +164,7 @@ func issues(ctx context.Context, c *client.Client, org string, project string, s
 				full, err = ghcache.IssuesGet(ctx, c.Cache, c.GitHubClient, t, org, project, i.GetNumber())
 			}
 			if err != nil {
-				klog.Errorf("failed IssuesGet: %v", err)
-				break
+				return result, touched, fmt.Errorf("get #%d: %w", i.GetNumber(), err)
 			}
 
 			creator := strings.ToLower(full.GetUser().GetLogin())
@@ -139,20 +175,18 @@ func issues(ctx context.Context, c *client.Client, org string, project string, s
 
 			result = append(result, full)
 		}
-	}
 
-	klog.Infof("Returning %d issues", len(result))
-	return result, nil
-}
-
-func issueDate(i *github.Issue) time.Time {
-	t := i.GetClosedAt()
-	if t.IsZero() {
-		t = i.GetUpdatedAt()
-	}
-	if t.IsZero() {
-		t = i.GetCreatedAt()
+		if cp != nil {
+			found := []int{}
+			for _, i := range result {
+				found = append(found, i.GetNumber())
+			}
+			if err := cp.SavePage(key, page, found); err != nil {
+				return result, touched, err
+			}
+		}
 	}
 
-	return t
+	klog.Infof("Returning %d issues", len(result))
+	return result, touched, nil
 }
@@ -20,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/go-github/v33/github"
 	"k8s.io/klog/v2"
 
 	"github.com/google/pullsheet/pkg/client"
@@ -37,13 +38,26 @@ type CommentSummary struct {
 	Comments    int
 	Words       int
 	Title       string
+	// RFC 3339 timestamps. CreatedAt and LastActivityAt are those of the first and last comments.
+	CreatedAt      string
+	ClosedAt       string
+	LastActivityAt string
 }
 
 // IssueComments returns a list of issue comment summaries
 func IssueComments(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, users []string) ([]*CommentSummary, error) {
-	is, err := issues(ctx, c, org, project, since, until, nil, "")
-	if err != nil {
-		return nil, fmt.Errorf("issues: %v", err)
+	reviews, _, err := IssueCommentsUpdated(ctx, c, org, project, since, until, since, users)
+	return reviews, err
+}
+
+// IssueCommentsUpdated returns a list of issue comment summaries, considering only issues updated at or after
+// updated. The URLs of all issues considered are also returned. On error, the comments summarized so far are
+// returned along with it.
+func IssueCommentsUpdated(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string) ([]*CommentSummary, []string, error) {
+	// If listing fails partway, the comments on the issues listed so far are still summarized
+	is, touched, listErr := issues(ctx, c, org, project, since, until, updated, nil, "")
+	if listErr != nil {
+		listErr = fmt.Errorf("issues: %v", listErr)
 	}
 
 	klog.Infof("found %d issues to check comments on", len(is))
@@ -54,26 +68,29 @@ func IssueComments(ctx context.Context, c *client.Client, org string, project st
 		matchUser[strings.ToLower(u)] = true
 	}
 
-	for _, i := range is {
+	for n, i := range is {
 		if i.IsPullRequest() {
 			continue
 		}
 
 		// username -> summary
 		iMap := map[string]*CommentSummary{}
+		first := map[string]time.Time{}
+		last := map[string]time.Time{}
 
-		cs, err := ghcache.IssuesListComments(ctx, c.Cache, c.GitHubClient, issueDate(i), org, project, i.GetNumber())
+		cs, err := ghcache.IssuesListComments(ctx, c.Cache, c.GitHubClient, i.GetUpdatedAt(), org, project, i.GetNumber())
 		if err != nil {
-			return nil, err
+			// Issues not summarized yet keep their earlier summaries
+			return reviews, without(touched, is[n:]), err
 		}
 
-		for _, c := range cs {
-			commenter := c.GetUser().GetLogin()
-			if c.CreatedAt.After(until) {
+		for _, ic := range cs {
+			commenter := ic.GetUser().GetLogin()
+			if ic.CreatedAt.After(until) {
 				continue
 			}
 
-			if c.CreatedAt.Before(since) {
+			if ic.CreatedAt.Before(since) {
 				continue
 			}
 
@@ -85,7 +102,7 @@ func IssueComments(ctx context.Context, c *client.Client, org string, project st
 				continue
 			}
 
-			if isBot(c.GetUser()) {
+			if isBot(c, ic.GetUser()) {
 				continue
 			}
 
@@ -95,7 +112,7 @@ func IssueComments(ctx context.Context, c *client.Client, org string, project st
 				continue
 			}
 
-			wordCount := wordCount(c.GetBody())
+			wordCount := wordCount(ic.GetBody())
 
 			if iMap[commenter] == nil {
 				iMap[commenter] = &CommentSummary{
@@ -105,13 +122,24 @@ func IssueComments(ctx context.Context, c *client.Client, org string, project st
 					Commenter:   commenter,
 					Project:     project,
 					Title:       strings.TrimSpace(i.GetTitle()),
+					ClosedAt:    Timestamp(i.GetClosedAt(), c.Location),
 				}
 			}
 
+			t := ic.GetCreatedAt()
+			if first[commenter].IsZero() || t.Before(first[commenter]) {
+				first[commenter] = t
+				iMap[commenter].CreatedAt = Timestamp(t, c.Location)
+			}
+			if t.After(last[commenter]) {
+				last[commenter] = t
+				iMap[commenter].Date = Date(t, c.Location)
+				iMap[commenter].LastActivityAt = Timestamp(t, c.Location)
+			}
+
 			iMap[commenter].Comments++
-			iMap[commenter].Date = c.CreatedAt.Format(dateForm)
 			iMap[commenter].Words += wordCount
-			klog.Infof("%d word comment by %s: %q for %s/%s #%d", wordCount, commenter, strings.TrimSpace(c.GetBody()), org, project, i.GetNumber())
+			klog.Infof("%d word comment by %s: %q for %s/%s #%d", wordCount, commenter, strings.TrimSpace(ic.GetBody()), org, project, i.GetNumber())
 		}
 
 		for _, rs := range iMap {
@@ -119,5 +147,21 @@ func IssueComments(ctx context.Context, c *client.Client, org string, project st
 		}
 	}
 
-	return reviews, err
+	return reviews, touched, listErr
+}
+
+// without returns the URLs which do not belong to any of the issues
+func without(urls []string, is []*github.Issue) []string {
+	skip := map[string]bool{}
+	for _, i := range is {
+		skip[i.GetHTMLURL()] = true
+	}
+
+	result := []string{}
+	for _, u := range urls {
+		if !skip[u] {
+			result = append(result, u)
+		}
+	}
+	return result
 }
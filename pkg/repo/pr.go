@@ -16,6 +16,7 @@ package repo
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
@@ -37,6 +38,24 @@ var (
 
 // MergedPulls returns a list of pull requests in a project
 func MergedPulls(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, users []string, branches []string) ([]*github.PullRequest, error) {
+	return MergedPullsUpdated(ctx, c, org, project, since, until, since, users, branches)
+}
+
+// MergedPullsUpdated returns a list of pull requests in a project, considering only those updated at or after updated.
+// On error, the pull requests found so far are returned along with it.
+func MergedPullsUpdated(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string, branches []string) ([]*github.PullRequest, error) {
+	if c.PullsStrategy == client.StrategySearch {
+		return mergedPullsSearch(ctx, c, org, project, since, until, updated, users, branches)
+	}
+
+	if c.Backend == client.BackendGraphQL {
+		prs, err := mergedPullsGraphQL(ctx, c, org, project, since, until, updated, users, branches)
+		if err == nil || ctx.Err() != nil {
+			return prs, err
+		}
+		klog.Warningf("GraphQL failed for %s/%s, falling back to REST: %v", org, project, err)
+	}
+
 	var result []*github.PullRequest
 
 	opts := &github.PullRequestListOptions{
@@ -53,13 +72,28 @@ func MergedPulls(ctx context.Context, c *client.Client, org string, project stri
 		matchUser[strings.ToLower(u)] = true
 	}
 
-	matchBranch := map[string]bool{}
-	for _, b := range branches {
-		matchBranch[strings.ToLower(b)] = true
+	matchBranch := branchMatcher(branches)
+
+	cp := pageCheckpoint(ctx)
+	key := listingKey("pulls", org, project, updated, users, branches)
+	start := 1
+	if cp != nil {
+		next, found, ok := cp.ResumePage(key)
+		if ok {
+			klog.Infof("Resuming %s/%s pull requests at page %d with %d found", org, project, next, len(found))
+			for _, num := range found {
+				pr, err := pullRequestsGet(ctx, c, time.Time{}, org, project, num)
+				if err != nil {
+					return result, err
+				}
+				result = append(result, pr)
+			}
+			start = next
+		}
 	}
 
 	klog.Infof("Gathering pull requests for %s/%s, users=%q: %+v", org, project, users, opts)
-	for page := 1; page != 0; {
+	for page := start; page != 0; {
 		opts.ListOptions.Page = page
 		prs, resp, err := c.GitHubClient.PullRequests.List(ctx, org, project, opts)
 		if err != nil {
@@ -71,7 +105,7 @@ func MergedPulls(ctx context.Context, c *client.Client, org string, project stri
 			break
 		}
 
-		klog.Infof("Processing page %d of %s/%s pull request results (looking for %s)...", page, org, project, since)
+		klog.Infof("Processing page %d of %s/%s pull request results (looking for %s)...", page, org, project, updated)
 
 		page = resp.NextPage
 		klog.Infof("Current PR updated at %s", prs[0].GetUpdatedAt())
@@ -81,7 +115,7 @@ func MergedPulls(ctx context.Context, c *client.Client, org string, project stri
 				continue
 			}
 
-			if pr.GetUpdatedAt().Before(since) {
+			if pr.GetUpdatedAt().Before(updated) {
 				klog.Infof("Hit PR#%d updated at %s", pr.GetNumber(), pr.GetUpdatedAt())
 				page = 0
 				break
@@ -96,7 +130,7 @@ func MergedPulls(ctx context.Context, c *client.Client, org string, project stri
 				continue
 			}
 
-			if isBot(pr.GetUser()) {
+			if isBot(c, pr.GetUser()) {
 				continue
 			}
 
@@ -106,39 +140,72 @@ func MergedPulls(ctx context.Context, c *client.Client, org string, project stri
 			}
 
 			klog.Infof("Fetching PR #%d by %s (updated %s): %q", pr.GetNumber(), pr.GetUser().GetLogin(), pr.GetUpdatedAt(), pr.GetTitle())
-			fullPR, err := ghcache.PullRequestsGet(ctx, c.Cache, c.GitHubClient, pr.GetMergedAt(), org, project, pr.GetNumber())
+			fullPR, err := pullRequestsGet(ctx, c, pr.GetUpdatedAt(), org, project, pr.GetNumber())
 			if err != nil {
-				time.Sleep(1 * time.Second)
-				fullPR, err = ghcache.PullRequestsGet(ctx, c.Cache, c.GitHubClient, pr.GetMergedAt(), org, project, pr.GetNumber())
-				if err != nil {
-					klog.Errorf("failed PullRequestsGet: %v", err)
-					break
-				}
+				return result, fmt.Errorf("get #%d: %w", pr.GetNumber(), err)
 			}
 
-			branch := fullPR.GetBase().GetRef()
-			if len(matchBranch) > 0 && !matchBranch[branch] {
-				klog.Errorf("#%d merged to %s, skipping", pr.GetNumber(), branch)
+			if !mergedInto(fullPR, since, matchBranch) {
 				continue
 			}
 
-			if !fullPR.GetMerged() || fullPR.GetMergeCommitSHA() == "" {
-				klog.Infof("#%d was not merged, skipping", pr.GetNumber())
-				continue
-			}
+			result = append(result, fullPR)
+		}
 
-			if pr.GetMergedAt().Before(since) {
-				klog.Infof("#%d was merged earlier than %s, skipping", pr.GetNumber(), since)
-				continue
+		if cp != nil {
+			found := []int{}
+			for _, pr := range result {
+				found = append(found, pr.GetNumber())
+			}
+			if err := cp.SavePage(key, page, found); err != nil {
+				return result, err
 			}
-
-			result = append(result, fullPR)
 		}
 	}
 	klog.Infof("Returning %d pull request results", len(result))
 	return result, nil
 }
 
+// pullRequestsGet fetches a full pull request, retrying once on failure
+func pullRequestsGet(ctx context.Context, c *client.Client, t time.Time, org string, project string, num int) (*github.PullRequest, error) {
+	pr, err := ghcache.PullRequestsGet(ctx, c.Cache, c.GitHubClient, t, org, project, num)
+	if err != nil {
+		time.Sleep(1 * time.Second)
+		pr, err = ghcache.PullRequestsGet(ctx, c.Cache, c.GitHubClient, t, org, project, num)
+	}
+	return pr, err
+}
+
+// branchMatcher returns a lookup table of lower-cased branch names
+func branchMatcher(branches []string) map[string]bool {
+	matchBranch := map[string]bool{}
+	for _, b := range branches {
+		matchBranch[strings.ToLower(b)] = true
+	}
+	return matchBranch
+}
+
+// mergedInto returns whether a full pull request was merged into a matching branch no earlier than since
+func mergedInto(pr *github.PullRequest, since time.Time, matchBranch map[string]bool) bool {
+	branch := pr.GetBase().GetRef()
+	if len(matchBranch) > 0 && !matchBranch[branch] {
+		klog.Errorf("#%d merged to %s, skipping", pr.GetNumber(), branch)
+		return false
+	}
+
+	if !pr.GetMerged() || pr.GetMergeCommitSHA() == "" {
+		klog.Infof("#%d was not merged, skipping", pr.GetNumber())
+		return false
+	}
+
+	if pr.GetMergedAt().Before(since) {
+		klog.Infof("#%d was merged earlier than %s, skipping", pr.GetNumber(), since)
+		return false
+	}
+
+	return true
+}
+
 // PRSummary is a summary of a single PR
 type PRSummary struct {
 	URL         string
@@ -153,10 +220,15 @@ type PRSummary struct {
 	FilesTotal  int
 	Files       string // newline delimited
 	Description string
+	// RFC 3339 timestamps
+	CreatedAt      string
+	MergedAt       string
+	ClosedAt       string
+	LastActivityAt string
 }
 
-// PullSummary converts GitHub PR data into a summarized view
-func PullSummary(prs map[*github.PullRequest][]github.CommitFile, since time.Time, until time.Time) ([]*PRSummary, error) {
+// PullSummary converts GitHub PR data into a summarized view, with dates and timestamps in loc
+func PullSummary(prs map[*github.PullRequest][]github.CommitFile, since time.Time, until time.Time, loc *time.Location) ([]*PRSummary, error) {
 	sum := []*PRSummary{}
 	seen := map[string]bool{}
 
@@ -210,7 +282,7 @@ func PullSummary(prs map[*github.PullRequest][]github.CommitFile, since time.Tim
 
 		sum = append(sum, &PRSummary{
 			URL:         pr.GetHTMLURL(),
-			Date:        t.Format(dateForm),
+			Date:        Date(t, loc),
 			Project:     project,
 			Type:        prType(files),
 			Title:       pr.GetTitle(),
@@ -221,6 +293,11 @@ func PullSummary(prs map[*github.PullRequest][]github.CommitFile, since time.Tim
 			FilesTotal:  pr.GetChangedFiles(),
 			Files:       strings.Join(paths, "\n"),
 			Description: body,
+
+			CreatedAt:      Timestamp(pr.GetCreatedAt(), loc),
+			MergedAt:       Timestamp(pr.GetMergedAt(), loc),
+			ClosedAt:       Timestamp(pr.GetClosedAt(), loc),
+			LastActivityAt: Timestamp(pr.GetUpdatedAt(), loc),
 		})
 	}
 
@@ -18,8 +18,28 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 )
 
+// Date returns the date of t in loc, as used by the Date fields of summaries. A nil loc is UTC.
+func Date(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(dateForm)
+}
+
+// Timestamp returns t in loc in RFC 3339 format, or "" if t is unset. A nil loc is UTC.
+func Timestamp(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
 // ParseURL returns the organization and project for a URL or partial path
 func ParseURL(rawURL string) (org string, project string) {
 	u, err := url.Parse(rawURL)
@@ -34,8 +34,7 @@ func ListRepoNames(ctx context.Context, c *client.Client, org string) ([]string,
 	for {
 		repos, resp, err := c.GitHubClient.Repositories.ListByOrg(ctx, org, opt)
 		if err != nil {
-			fmt.Println(err)
-			return allRepos, err
+			return allRepos, fmt.Errorf("list repos: %w", err)
 		}
 
 		for _, val := range repos {
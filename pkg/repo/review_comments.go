@@ -25,7 +25,6 @@ import (
 
 	"github.com/blevesearch/segment"
 	"github.com/google/go-github/v33/github"
-	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
 
 	"github.com/google/pullsheet/pkg/client"
@@ -45,6 +44,10 @@ type ReviewSummary struct {
 	ReviewComments int
 	Words          int
 	Title          string
+	// RFC 3339 timestamps. CreatedAt and LastActivityAt are those of the reviewer's first and last comments.
+	CreatedAt      string
+	MergedAt       string
+	LastActivityAt string
 }
 
 type comment struct {
@@ -56,9 +59,18 @@ type comment struct {
 
 // MergedReviews returns a list of pull requests in a project (merged only)
 func MergedReviews(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, users []string) ([]*ReviewSummary, error) {
-	prs, err := MergedPulls(ctx, c, org, project, since, until, nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("pulls: %v", err)
+	reviews, _, err := MergedReviewsUpdated(ctx, c, org, project, since, until, since, users)
+	return reviews, err
+}
+
+// MergedReviewsUpdated returns reviews on merged pull requests in a project, considering only pull requests
+// updated at or after updated. The URLs of all pull requests considered are also returned. On error, the reviews
+// summarized so far are returned along with it.
+func MergedReviewsUpdated(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string) ([]*ReviewSummary, []string, error) {
+	// If listing fails partway, the reviews of the pull requests listed so far are still summarized
+	prs, listErr := MergedPullsUpdated(ctx, c, org, project, since, until, updated, nil, nil)
+	if listErr != nil {
+		listErr = fmt.Errorf("pulls: %v", listErr)
 	}
 
 	klog.Infof("found %d PR's in %s/%s to find reviews for", len(prs), org, project)
@@ -69,19 +81,22 @@ func MergedReviews(ctx context.Context, c *client.Client, org string, project st
 		matchUser[strings.ToLower(u)] = true
 	}
 
+	touched := []string{}
 	for _, pr := range prs {
 		// username -> summary
 		prMap := map[string]*ReviewSummary{}
+		first := map[string]time.Time{}
+		last := map[string]time.Time{}
 		comments := []comment{}
 
 		// There is wickedness in the GitHub API: PR comments are available via the Issues API, and PR *review* comments are available via the PullRequests API
-		cs, err := ghcache.PullRequestsListComments(ctx, c.Cache, c.GitHubClient, pr.GetMergedAt(), org, project, pr.GetNumber())
+		cs, err := ghcache.PullRequestsListComments(ctx, c.Cache, c.GitHubClient, pr.GetUpdatedAt(), org, project, pr.GetNumber())
 		if err != nil {
-			return nil, err
+			return reviews, touched, err
 		}
 
 		for idx := range cs {
-			if isBot(cs[idx].GetUser()) {
+			if isBot(c, cs[idx].GetUser()) {
 				continue
 			}
 
@@ -89,13 +104,13 @@ func MergedReviews(ctx context.Context, c *client.Client, org string, project st
 			comments = append(comments, comment{Author: cs[idx].GetUser().GetLogin(), Body: body, CreatedAt: cs[idx].GetCreatedAt(), Review: true})
 		}
 
-		is, err := ghcache.IssuesListComments(ctx, c.Cache, c.GitHubClient, pr.GetMergedAt(), org, project, pr.GetNumber())
+		is, err := ghcache.IssuesListComments(ctx, c.Cache, c.GitHubClient, pr.GetUpdatedAt(), org, project, pr.GetNumber())
 		if err != nil {
-			return nil, err
+			return reviews, touched, err
 		}
 
 		for _, i := range is {
-			if isBot(i.GetUser()) {
+			if isBot(c, i.GetUser()) {
 				continue
 			}
 
@@ -108,52 +123,62 @@ func MergedReviews(ctx context.Context, c *client.Client, org string, project st
 			comments = append(comments, comment{Author: i.GetUser().GetLogin(), Body: body, CreatedAt: i.GetCreatedAt(), Review: false})
 		}
 
-		for _, c := range comments {
-			if c.CreatedAt.After(until) {
+		for _, cm := range comments {
+			if cm.CreatedAt.After(until) {
 				continue
 			}
 
-			if c.CreatedAt.Before(since) {
+			if cm.CreatedAt.Before(since) {
 				continue
 			}
 
-			if len(matchUser) > 0 && !matchUser[strings.ToLower(c.Author)] {
+			if len(matchUser) > 0 && !matchUser[strings.ToLower(cm.Author)] {
 				continue
 			}
 
-			if c.Author == pr.GetUser().GetLogin() {
+			if cm.Author == pr.GetUser().GetLogin() {
 				continue
 			}
 
-			wordCount := wordCount(c.Body)
+			wordCount := wordCount(cm.Body)
 
-			if prMap[c.Author] == nil {
-				prMap[c.Author] = &ReviewSummary{
+			if prMap[cm.Author] == nil {
+				prMap[cm.Author] = &ReviewSummary{
 					URL:      pr.GetHTMLURL(),
 					PRAuthor: pr.GetUser().GetLogin(),
-					Reviewer: c.Author,
+					Reviewer: cm.Author,
 					Project:  project,
 					Title:    strings.TrimSpace(pr.GetTitle()),
+					MergedAt: Timestamp(pr.GetMergedAt(), c.Location),
 				}
 			}
 
-			if c.Review {
-				prMap[c.Author].ReviewComments++
+			if cm.Review {
+				prMap[cm.Author].ReviewComments++
 			} else {
-				prMap[c.Author].PRComments++
+				prMap[cm.Author].PRComments++
 			}
 
-			prMap[c.Author].Date = c.CreatedAt.Format(dateForm)
-			prMap[c.Author].Words += wordCount
-			klog.Infof("%d word comment by %s: %q for %s/%s #%d", wordCount, c.Author, strings.TrimSpace(c.Body), org, project, pr.GetNumber())
+			if first[cm.Author].IsZero() || cm.CreatedAt.Before(first[cm.Author]) {
+				first[cm.Author] = cm.CreatedAt
+				prMap[cm.Author].CreatedAt = Timestamp(cm.CreatedAt, c.Location)
+			}
+			if cm.CreatedAt.After(last[cm.Author]) {
+				last[cm.Author] = cm.CreatedAt
+				prMap[cm.Author].Date = Date(cm.CreatedAt, c.Location)
+				prMap[cm.Author].LastActivityAt = Timestamp(cm.CreatedAt, c.Location)
+			}
+			prMap[cm.Author].Words += wordCount
+			klog.Infof("%d word comment by %s: %q for %s/%s #%d", wordCount, cm.Author, strings.TrimSpace(cm.Body), org, project, pr.GetNumber())
 		}
 
 		for _, rs := range prMap {
 			reviews = append(reviews, rs)
 		}
+		touched = append(touched, pr.GetHTMLURL())
 	}
 
-	return reviews, err
+	return reviews, touched, listErr
 }
 
 // wordCount counts words in a string, irrespective of language
@@ -173,9 +198,9 @@ func wordCount(s string) int {
 	return words
 }
 
-func isBot(u *github.User) bool {
-	includeBots := viper.GetBool("include-bots")
-	if includeBots {
+// isBot returns whether a user is a bot whose activity should be excluded, according to the client's bot policy
+func isBot(c *client.Client, u *github.User) bool {
+	if c.IncludeBots {
 		return false
 	}
 	if u.GetType() == "bot" {
@@ -0,0 +1,229 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+)
+
+const (
+	// searchResultCap is the maximum number of results GitHub returns for a single search query
+	searchResultCap = 1000
+	// searchTimeForm is the timestamp format accepted by search qualifiers
+	searchTimeForm = "2006-01-02T15:04:05Z"
+)
+
+// mergedPullsSearch returns a list of merged pull requests in a project, located using the Search API
+func mergedPullsSearch(ctx context.Context, c *client.Client, org string, project string, since time.Time, until time.Time, updated time.Time, users []string, branches []string) ([]*github.PullRequest, error) {
+	s := &pullSearch{
+		c:           c,
+		org:         org,
+		project:     project,
+		since:       since,
+		until:       until,
+		matchBranch: branchMatcher(branches),
+		cp:          pageCheckpoint(ctx),
+		seen:        map[int]bool{},
+	}
+
+	// Query each user separately so that every author gets the full search result cap
+	authors := users
+	if len(authors) == 0 {
+		authors = []string{""}
+	}
+
+	for _, author := range authors {
+		query := fmt.Sprintf("is:pr is:merged repo:%s/%s", org, project)
+		if author != "" {
+			query += " author:" + author
+		}
+		if updated.After(since) {
+			query += " updated:>=" + updated.UTC().Format(searchTimeForm)
+		}
+
+		klog.Infof("Searching pull requests for %s/%s, author=%q", org, project, author)
+		s.key = listingKey("search-pulls", org, project, updated, []string{author}, branches)
+		if err := s.window(ctx, query, since, until); err != nil {
+			return s.result, err
+		}
+	}
+
+	klog.Infof("Returning %d pull request results", len(s.result))
+	if len(s.truncated) > 0 {
+		return s.result, fmt.Errorf("search results truncated at %d: %s", searchResultCap, strings.Join(s.truncated, ", "))
+	}
+	return s.result, nil
+}
+
+// pullSearch collects the merged pull requests matching search queries, fetching each in full as its page of
+// results arrives
+type pullSearch struct {
+	c           *client.Client
+	org         string
+	project     string
+	since       time.Time // Window of the run, which queries may split further
+	until       time.Time
+	matchBranch map[string]bool
+	cp          PageCheckpoint
+	key         string // Listing key of the current query, to which the bounds of each window are added
+
+	seen      map[int]bool
+	result    []*github.PullRequest
+	truncated []string // Windows whose results exceed the cap and could not be split
+}
+
+// window adds the pull requests matching a query which were merged within [since, until]. Windows with more results
+// than the Search API returns are split in half until each part fits. The progress of each window that is not split
+// is checkpointed by page.
+func (s *pullSearch) window(ctx context.Context, query string, since time.Time, until time.Time) error {
+	q := fmt.Sprintf("%s merged:%s..%s", query, since.UTC().Format(searchTimeForm), until.UTC().Format(searchTimeForm))
+	key := s.key + "/" + since.UTC().Format(searchTimeForm) + ".." + until.UTC().Format(searchTimeForm)
+	opts := &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	found := []int{}
+	start := 1
+	if s.cp != nil {
+		next, nums, ok := s.cp.ResumePage(key)
+		if ok {
+			klog.Infof("Resuming search %q at page %d with %d found", q, next, len(nums))
+			for _, num := range nums {
+				pr, err := pullRequestsGet(ctx, s.c, time.Time{}, s.org, s.project, num)
+				if err != nil {
+					return err
+				}
+				s.add(pr)
+				found = append(found, num)
+			}
+			start = next
+		}
+	}
+
+	for page := start; page != 0; {
+		opts.ListOptions.Page = page
+		sr, resp, err := searchWithRetry(ctx, s.c, q, opts)
+		if err != nil {
+			return err
+		}
+
+		if page == 1 && sr.GetTotal() > searchResultCap && until.Sub(since) > time.Second {
+			mid := since.Add(until.Sub(since) / 2).Truncate(time.Second)
+			klog.Infof("%d results for %q exceeds %d, splitting at %s", sr.GetTotal(), q, searchResultCap, mid)
+
+			if err := s.window(ctx, query, since, mid); err != nil {
+				return err
+			}
+			return s.window(ctx, query, mid.Add(time.Second), until)
+		}
+
+		// A window this short cannot be split further, so the search only returns its first results
+		if page == 1 && sr.GetTotal() > searchResultCap {
+			s.truncated = append(s.truncated, fmt.Sprintf("%q has %d", q, sr.GetTotal()))
+			klog.Warningf("%d results for %q exceeds %d and cannot be split, results are truncated", sr.GetTotal(), q, searchResultCap)
+		}
+
+		klog.Infof("Processing page %d of %d search results for %q", page, sr.GetTotal(), q)
+		for _, i := range sr.Issues {
+			if s.seen[i.GetNumber()] {
+				continue
+			}
+			s.seen[i.GetNumber()] = true
+
+			if isBot(s.c, i.GetUser()) {
+				continue
+			}
+
+			klog.Infof("Fetching PR #%d by %s (updated %s): %q", i.GetNumber(), i.GetUser().GetLogin(), i.GetUpdatedAt(), i.GetTitle())
+			fullPR, err := pullRequestsGet(ctx, s.c, i.GetUpdatedAt(), s.org, s.project, i.GetNumber())
+			if err != nil {
+				return fmt.Errorf("get #%d: %w", i.GetNumber(), err)
+			}
+
+			if fullPR.GetMergedAt().After(s.until) {
+				continue
+			}
+
+			if !mergedInto(fullPR, s.since, s.matchBranch) {
+				continue
+			}
+
+			s.result = append(s.result, fullPR)
+			found = append(found, fullPR.GetNumber())
+		}
+
+		page = resp.NextPage
+		if s.cp != nil {
+			if err := s.cp.SavePage(key, page, found); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// add adds a pull request found by an earlier, interrupted search
+func (s *pullSearch) add(pr *github.PullRequest) {
+	if s.seen[pr.GetNumber()] {
+		return
+	}
+	s.seen[pr.GetNumber()] = true
+	s.result = append(s.result, pr)
+}
+
+// searchWithRetry runs a search query, waiting out the (low) Search API rate limit if it is hit
+func searchWithRetry(ctx context.Context, c *client.Client, q string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	for {
+		sr, resp, err := c.GitHubClient.Search.Issues(ctx, q, opts)
+		if err == nil {
+			return sr, resp, nil
+		}
+
+		var wait time.Duration
+		var rle *github.RateLimitError
+		var arle *github.AbuseRateLimitError
+		switch {
+		case errors.As(err, &rle):
+			wait = time.Until(rle.Rate.Reset.Time) + time.Second
+		case errors.As(err, &arle):
+			wait = arle.GetRetryAfter()
+		default:
+			return nil, nil, fmt.Errorf("search %q: %w", q, err)
+		}
+
+		if wait <= 0 {
+			wait = 10 * time.Second
+		}
+
+		klog.Warningf("search rate limit hit, sleeping %s: %v", wait, err)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
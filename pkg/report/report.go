@@ -0,0 +1,210 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report renders the contributions of one person, such as for promotion discussions.
+package report
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/pullsheet/pkg/print"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+const dateForm = "2006-01-02"
+
+// Options to use for rendering a report
+type Options struct {
+	User  string
+	Title string // Defaults to the user
+	Since time.Time
+	Until time.Time
+	// Shown at the top of the report, such as when the data is incomplete
+	Warnings []string
+}
+
+// Stat is a total for the user, compared to everyone else with activity
+type Stat struct {
+	Name         string
+	Value        int
+	Percentile   int // Percentage of the other contributors whose total is lower
+	Contributors int // Contributors with activity of this kind, including the user
+}
+
+// PRGroup is the user's merged pull requests in a project of one type
+type PRGroup struct {
+	Project string
+	Type    string
+	Delta   int
+	PRs     []*repo.PRSummary
+}
+
+type report struct {
+	Options
+	From     string
+	Until    string
+	Stats    []Stat
+	PRGroups []PRGroup
+	Reviews  []*repo.ReviewSummary
+	Issues   []*repo.IssueSummary
+	Comments []*repo.CommentSummary
+}
+
+// Markdown returns the report of a user's contributions as GitHub-flavored Markdown. Summaries are those of
+// everyone, against whom the user's totals are compared.
+func Markdown(options Options, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) (string, error) {
+	tmpl, err := template.New("Markdown").Funcs(template.FuncMap{"md": print.MarkdownEscape}).Parse(markdownTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse: %v", err)
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, build(options, prs, reviews, issues, comments)); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+	return tpl.String(), nil
+}
+
+// HTML returns the report of a user's contributions as an HTML page, as Markdown does
+func HTML(options Options, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) (string, error) {
+	tmpl, err := htmltemplate.New("HTML").Parse(htmlTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse: %v", err)
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, build(options, prs, reviews, issues, comments)); err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+	return tpl.String(), nil
+}
+
+// build returns the contents of a report
+func build(options Options, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) *report {
+	if options.Title == "" {
+		options.Title = options.User
+	}
+
+	r := &report{
+		Options: options,
+		From:    options.Since.Format(dateForm),
+		Until:   options.Until.Format(dateForm),
+	}
+	is := func(u string) bool { return strings.EqualFold(u, options.User) }
+
+	prCounts, deltas := map[string]int{}, map[string]int{}
+	groups := map[string]*PRGroup{}
+	for _, pr := range prs {
+		prCounts[pr.User]++
+		deltas[pr.User] += pr.Delta
+		if !is(pr.User) {
+			continue
+		}
+
+		key := pr.Project + "/" + pr.Type
+		if groups[key] == nil {
+			groups[key] = &PRGroup{Project: pr.Project, Type: pr.Type}
+		}
+		groups[key].PRs = append(groups[key].PRs, pr)
+		groups[key].Delta += pr.Delta
+	}
+	for _, g := range groups {
+		sort.Slice(g.PRs, func(i, j int) bool { return g.PRs[i].Date < g.PRs[j].Date })
+		r.PRGroups = append(r.PRGroups, *g)
+	}
+	sort.Slice(r.PRGroups, func(i, j int) bool {
+		if r.PRGroups[i].Project != r.PRGroups[j].Project {
+			return r.PRGroups[i].Project < r.PRGroups[j].Project
+		}
+		return r.PRGroups[i].Type < r.PRGroups[j].Type
+	})
+
+	reviewCounts, reviewWords := map[string]int{}, map[string]int{}
+	for _, rv := range reviews {
+		reviewCounts[rv.Reviewer]++
+		reviewWords[rv.Reviewer] += rv.Words
+		if is(rv.Reviewer) {
+			r.Reviews = append(r.Reviews, rv)
+		}
+	}
+	sort.Slice(r.Reviews, func(i, j int) bool { return r.Reviews[i].Words > r.Reviews[j].Words })
+
+	closed := map[string]int{}
+	for _, i := range issues {
+		closed[i.Closer]++
+		if is(i.Closer) {
+			r.Issues = append(r.Issues, i)
+		}
+	}
+	sort.Slice(r.Issues, func(i, j int) bool { return r.Issues[i].Date < r.Issues[j].Date })
+
+	commentCounts, commentWords := map[string]int{}, map[string]int{}
+	for _, c := range comments {
+		commentCounts[c.Commenter] += c.Comments
+		commentWords[c.Commenter] += c.Words
+		if is(c.Commenter) {
+			r.Comments = append(r.Comments, c)
+		}
+	}
+	sort.Slice(r.Comments, func(i, j int) bool { return r.Comments[i].Words > r.Comments[j].Words })
+
+	r.Stats = []Stat{
+		stat("Pull requests merged", prCounts, options.User),
+		stat("Lines changed", deltas, options.User),
+		stat("Pull requests reviewed", reviewCounts, options.User),
+		stat("Words written in reviews", reviewWords, options.User),
+		stat("Issues closed", closed, options.User),
+		stat("Issue comments", commentCounts, options.User),
+		stat("Words written in issue comments", commentWords, options.User),
+	}
+	return r
+}
+
+// stat returns the total of a user, and how it compares to the totals of everyone else
+func stat(name string, totals map[string]int, user string) Stat {
+	s := Stat{Name: name}
+	others := []int{}
+	for u, v := range totals {
+		if strings.EqualFold(u, user) {
+			s.Value += v
+			continue
+		}
+		others = append(others, v)
+	}
+
+	s.Contributors = len(others)
+	if s.Value > 0 {
+		s.Contributors++
+	}
+
+	lower := 0
+	for _, v := range others {
+		if v < s.Value {
+			lower++
+		}
+	}
+	switch {
+	case len(others) > 0:
+		s.Percentile = lower * 100 / len(others)
+	case s.Value > 0:
+		s.Percentile = 100
+	}
+	return s
+}
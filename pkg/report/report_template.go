@@ -0,0 +1,188 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+const markdownTmpl = `# {{ md .Title }}
+
+Contributions by @{{ md .User }} from {{ .From }} to {{ .Until }}.
+{{ range .Warnings }}
+> **Warning:** {{ . }}
+{{ end }}
+## Summary
+
+| | Total | Ahead of |
+|---|---:|---:|
+{{ range .Stats }}| {{ .Name }} | {{ .Value }} | {{ .Percentile }}% of {{ .Contributors }} contributors |
+{{ end }}
+## Merged pull requests
+{{ range .PRGroups }}
+### {{ md .Project }}{{ if .Type }}: {{ md .Type }}{{ end }} ({{ len .PRs }} merged, {{ .Delta }} lines changed)
+
+| Merged | Pull request | Added | Deleted | Files |
+|---|---|---:|---:|---:|
+{{ range .PRs }}| {{ .Date }} | [{{ md .Title }}]({{ .URL }}) | {{ .Added }} | {{ .Deleted }} | {{ .FilesTotal }} |
+{{ end }}{{ else }}
+None.
+{{ end }}
+## Reviews
+{{ if .Reviews }}
+| Date | Pull request | Author | Comments | Words |
+|---|---|---|---:|---:|
+{{ range .Reviews }}| {{ .Date }} | [{{ md .Title }}]({{ .URL }}) | @{{ md .PRAuthor }} | {{ .ReviewComments }} review, {{ .PRComments }} other | {{ .Words }} |
+{{ end }}{{ else }}
+None.
+{{ end }}
+## Closed issues
+{{ if .Issues }}
+| Closed | Issue | Author |
+|---|---|---|
+{{ range .Issues }}| {{ .Date }} | [{{ md .Title }}]({{ .URL }}) | @{{ md .Author }} |
+{{ end }}{{ else }}
+None.
+{{ end }}
+## Issue comments
+{{ if .Comments }}
+| Date | Issue | Author | Comments | Words |
+|---|---|---|---:|---:|
+{{ range .Comments }}| {{ .Date }} | [{{ md .Title }}]({{ .URL }}) | @{{ md .IssueAuthor }} | {{ .Comments }} | {{ .Words }} |
+{{ end }}{{ else }}
+None.
+{{ end }}`
+
+const htmlTmpl = `<html>
+<head>
+    <title>{{ .Title }} - Contributions</title>
+    <link rel="preconnect" href="https://fonts.gstatic.com">
+    <link href="https://fonts.googleapis.com/css2?family=Open+Sans:wght@300;400;600;700&display=swap" rel="stylesheet">
+    <style>
+    body {
+       font-family: 'Open Sans', sans-serif;
+       background-color: #f7f7fa;
+       padding: 1em;
+    }
+
+    h1 {
+      color: rgba(66,133,244);
+      margin-bottom: 0em;
+    }
+
+    h2 {
+        color: #333;
+    }
+
+    h3 {
+        color: #666;
+    }
+
+    .subtitle {
+      color: rgba(23,90,201);
+      font-size: small;
+    }
+
+    table {
+        border-collapse: collapse;
+        background-color: #fff;
+        margin-bottom: 1em;
+    }
+
+    th, td {
+        padding: 0.3em 0.8em;
+        border: 1px solid rgba(66,133,244,0.25);
+        text-align: left;
+        font-size: small;
+    }
+
+    td.n {
+        text-align: right;
+    }
+
+    a {
+        color: rgba(23,90,201);
+    }
+
+    .warning {
+        margin: 1em 0;
+        padding: 0.5em 1em;
+        border: 2px solid rgba(219,68,55,0.5);
+        background-color: rgba(219,68,55,0.08);
+    }
+    </style>
+</head>
+<body>
+    <h1>{{ .Title }}</h1>
+    <div class="subtitle">Contributions by {{ .User }} from {{ .From }} &mdash; {{ .Until }}</div>
+{{ range .Warnings }}
+    <div class="warning">{{ . }}</div>
+{{ end }}
+
+    <h2>Summary</h2>
+    <table>
+        <tr><th></th><th>Total</th><th>Ahead of</th></tr>
+    {{ range .Stats }}
+        <tr><td>{{ .Name }}</td><td class="n">{{ .Value }}</td><td class="n">{{ .Percentile }}% of {{ .Contributors }} contributors</td></tr>
+    {{ end }}
+    </table>
+
+    <h2>Merged pull requests</h2>
+{{ range .PRGroups }}
+    <h3>{{ .Project }}{{ if .Type }}: {{ .Type }}{{ end }} ({{ len .PRs }} merged, {{ .Delta }} lines changed)</h3>
+    <table>
+        <tr><th>Merged</th><th>Pull request</th><th>Added</th><th>Deleted</th><th>Files</th></tr>
+    {{ range .PRs }}
+        <tr><td>{{ .Date }}</td><td><a href="{{ .URL }}">{{ .Title }}</a></td><td class="n">{{ .Added }}</td><td class="n">{{ .Deleted }}</td><td class="n">{{ .FilesTotal }}</td></tr>
+    {{ end }}
+    </table>
+{{ else }}
+    <p>None.</p>
+{{ end }}
+
+    <h2>Reviews</h2>
+{{ if .Reviews }}
+    <table>
+        <tr><th>Date</th><th>Pull request</th><th>Author</th><th>Comments</th><th>Words</th></tr>
+    {{ range .Reviews }}
+        <tr><td>{{ .Date }}</td><td><a href="{{ .URL }}">{{ .Title }}</a></td><td>{{ .PRAuthor }}</td><td>{{ .ReviewComments }} review, {{ .PRComments }} other</td><td class="n">{{ .Words }}</td></tr>
+    {{ end }}
+    </table>
+{{ else }}
+    <p>None.</p>
+{{ end }}
+
+    <h2>Closed issues</h2>
+{{ if .Issues }}
+    <table>
+        <tr><th>Closed</th><th>Issue</th><th>Author</th></tr>
+    {{ range .Issues }}
+        <tr><td>{{ .Date }}</td><td><a href="{{ .URL }}">{{ .Title }}</a></td><td>{{ .Author }}</td></tr>
+    {{ end }}
+    </table>
+{{ else }}
+    <p>None.</p>
+{{ end }}
+
+    <h2>Issue comments</h2>
+{{ if .Comments }}
+    <table>
+        <tr><th>Date</th><th>Issue</th><th>Author</th><th>Comments</th><th>Words</th></tr>
+    {{ range .Comments }}
+        <tr><td>{{ .Date }}</td><td><a href="{{ .URL }}">{{ .Title }}</a></td><td>{{ .IssueAuthor }}</td><td class="n">{{ .Comments }}</td><td class="n">{{ .Words }}</td></tr>
+    {{ end }}
+    </table>
+{{ else }}
+    <p>None.</p>
+{{ end }}
+</body>
+</html>
+`
@@ -23,12 +23,14 @@ import (
 
 	"github.com/google/pullsheet/pkg/client"
 	"github.com/google/pullsheet/pkg/leaderboard"
+	"github.com/google/pullsheet/pkg/summary"
 )
 
 // Job represents a job to be run by the server
 type Job struct {
-	opts *Opts
-	u    *updater
+	opts     *Opts
+	u        *updater
+	progress *summary.Progress
 }
 
 // Opts Options related to the Job
@@ -40,6 +42,7 @@ type Opts struct {
 	Until          time.Time // Until when to query
 	Title          string    // Title of the leaderboard
 	DisableCaching bool      // Disable caching
+	Command        string    // Command line shown on the leaderboard, if any
 }
 
 // New creates a new Job
@@ -50,6 +53,7 @@ func New(opts *Opts) *Job {
 			mu:   &sync.Mutex{},
 			data: data{},
 		},
+		progress: summary.NewProgress(),
 	}
 }
 
@@ -67,6 +71,7 @@ func (j *Job) Render() (string, error) {
 		Since:          j.opts.Since,
 		Until:          j.opts.Until,
 		DisableCaching: j.opts.DisableCaching,
+		Command:        j.opts.Command,
 	}, j.opts.Users, d.prs, d.reviews, d.issues, d.comments)
 	if err != nil {
 		return "", err
@@ -77,12 +82,17 @@ func (j *Job) Render() (string, error) {
 
 // Update updates the Job
 func (j *Job) Update(ctx context.Context, cl *client.Client) {
-	err := j.u.updateData(ctx, cl, j.opts)
+	err := j.u.updateData(summary.WithProgress(ctx, j.progress), cl, j.opts)
 	if err != nil {
 		klog.Errorf("Failed to update job: %d", err)
 	}
 }
 
+// Progress returns how far the Job's update has got
+func (j *Job) Progress() summary.ProgressStats {
+	return j.progress.Stats()
+}
+
 // GetOpts returns the options for the Job
 func (j *Job) GetOpts() Opts {
 	return *j.opts
@@ -21,6 +21,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/pullsheet/pkg/client"
 	"github.com/google/pullsheet/pkg/server/job"
@@ -113,11 +114,11 @@ func (s *Server) NewJob() http.HandlerFunc {
 			since := r.FormValue("since")
 			until := r.FormValue("until")
 
-			sinceParsed, err := tparse.ParseNow(dateForm, since)
+			sinceParsed, err := s.parseTime(since)
 			if err != nil {
 				klog.Errorf("Parsing from: %d", err)
 			}
-			untilParsed, err := tparse.ParseNow(dateForm, until)
+			untilParsed, err := s.parseTime(until)
 			if err != nil {
 				klog.Errorf("Parsing from: %d", err)
 			}
@@ -138,6 +139,18 @@ func (s *Server) NewJob() http.HandlerFunc {
 	}
 }
 
+// parseTime parses a date, in the time zone of the client, or a duration such as now-90d
+func (s *Server) parseTime(v string) (time.Time, error) {
+	loc := s.cl.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t, err := time.ParseInLocation(dateForm, v, loc); err == nil {
+		return t, nil
+	}
+	return tparse.ParseNow(dateForm, v)
+}
+
 // AddJob adds a job to the server
 func (s *Server) AddJob(ctx context.Context, j *job.Job) {
 	s.jobs = append(s.jobs, j)
@@ -12,7 +12,8 @@ import (
 var content embed.FS
 
 type jobData struct {
-	Title string
+	Title    string
+	Progress string
 }
 
 // Home returns the home page
@@ -20,7 +21,8 @@ func Home(jobs []*job.Job) (string, error) {
 	jData := []jobData{}
 	for _, job := range jobs {
 		jData = append(jData, jobData{
-			Title: job.GetOpts().Title,
+			Title:    job.GetOpts().Title,
+			Progress: job.Progress().String(),
 		})
 	}
 
@@ -0,0 +1,363 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlite writes summaries into a SQLite database, for analysis with SQL
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	// Registers the "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// schema creates the tables and indexes of a database, unless they already exist
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at TEXT NOT NULL,
+	command TEXT,
+	repos TEXT,
+	users TEXT,
+	branches TEXT,
+	since TEXT,
+	until TEXT,
+	timezone TEXT,
+	incomplete INTEGER NOT NULL DEFAULT 0,
+	reason TEXT,
+	errors TEXT
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	login TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS prs (
+	url TEXT PRIMARY KEY,
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	date TEXT,
+	user TEXT REFERENCES users(login),
+	project TEXT,
+	type TEXT,
+	title TEXT,
+	delta INTEGER,
+	added INTEGER,
+	deleted INTEGER,
+	files_total INTEGER,
+	description TEXT,
+	created_at TEXT,
+	merged_at TEXT,
+	closed_at TEXT,
+	last_activity_at TEXT
+);
+CREATE INDEX IF NOT EXISTS prs_user ON prs(user);
+CREATE INDEX IF NOT EXISTS prs_project_date ON prs(project, date);
+
+CREATE TABLE IF NOT EXISTS pr_files (
+	pr_url TEXT NOT NULL REFERENCES prs(url) ON DELETE CASCADE,
+	path TEXT NOT NULL,
+	PRIMARY KEY (pr_url, path)
+);
+CREATE INDEX IF NOT EXISTS pr_files_path ON pr_files(path);
+
+CREATE TABLE IF NOT EXISTS reviews (
+	url TEXT NOT NULL,
+	reviewer TEXT NOT NULL REFERENCES users(login),
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	date TEXT,
+	project TEXT,
+	pr_author TEXT REFERENCES users(login),
+	pr_comments INTEGER,
+	review_comments INTEGER,
+	words INTEGER,
+	title TEXT,
+	created_at TEXT,
+	merged_at TEXT,
+	last_activity_at TEXT,
+	PRIMARY KEY (url, reviewer)
+);
+CREATE INDEX IF NOT EXISTS reviews_reviewer ON reviews(reviewer);
+CREATE INDEX IF NOT EXISTS reviews_project_date ON reviews(project, date);
+
+CREATE TABLE IF NOT EXISTS issues (
+	url TEXT PRIMARY KEY,
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	date TEXT,
+	author TEXT REFERENCES users(login),
+	closer TEXT REFERENCES users(login),
+	project TEXT,
+	type TEXT,
+	title TEXT,
+	created_at TEXT,
+	closed_at TEXT,
+	last_activity_at TEXT
+);
+CREATE INDEX IF NOT EXISTS issues_closer ON issues(closer);
+CREATE INDEX IF NOT EXISTS issues_project_date ON issues(project, date);
+
+CREATE TABLE IF NOT EXISTS comments (
+	url TEXT NOT NULL,
+	commenter TEXT NOT NULL REFERENCES users(login),
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	date TEXT,
+	project TEXT,
+	issue_author TEXT REFERENCES users(login),
+	issue_state TEXT,
+	comments INTEGER,
+	words INTEGER,
+	title TEXT,
+	created_at TEXT,
+	closed_at TEXT,
+	last_activity_at TEXT,
+	PRIMARY KEY (url, commenter)
+);
+CREATE INDEX IF NOT EXISTS comments_commenter ON comments(commenter);
+CREATE INDEX IF NOT EXISTS comments_project_date ON comments(project, date);
+`
+
+// Run describes the run whose results are written, so that results of several windows can be told apart
+type Run struct {
+	Command    string
+	Repos      []string
+	Users      []string
+	Branches   []string
+	Since      time.Time
+	Until      time.Time
+	Location   *time.Location
+	Incomplete bool     // Whether the run stopped before collecting everything
+	Reason     string   // Why the run stopped early
+	Errors     []string // Data that could not be collected
+}
+
+// Data is the summaries to write. Any of them may be empty.
+type Data struct {
+	PRs      []*repo.PRSummary
+	Reviews  []*repo.ReviewSummary
+	Issues   []*repo.IssueSummary
+	Comments []*repo.CommentSummary
+}
+
+// Write records a run and its summaries in the database at path, creating it if needed. Summaries already in the
+// database are replaced by those with the same URL (and reviewer or commenter). The ID of the run is returned.
+func Write(ctx context.Context, path string, run Run, d Data) (int64, error) {
+	// Foreign keys are enabled in the DSN, so that every pooled connection enforces them
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %v", path, err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return 0, fmt.Errorf("schema: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			klog.Errorf("rollback: %v", err)
+		}
+	}()
+
+	id, err := insertRun(ctx, tx, run)
+	if err != nil {
+		return 0, fmt.Errorf("runs: %v", err)
+	}
+	if err := insertUsers(ctx, tx, d); err != nil {
+		return 0, fmt.Errorf("users: %v", err)
+	}
+	if err := upsertPRs(ctx, tx, id, d.PRs); err != nil {
+		return 0, fmt.Errorf("prs: %v", err)
+	}
+	if err := upsertReviews(ctx, tx, id, d.Reviews); err != nil {
+		return 0, fmt.Errorf("reviews: %v", err)
+	}
+	if err := upsertIssues(ctx, tx, id, d.Issues); err != nil {
+		return 0, fmt.Errorf("issues: %v", err)
+	}
+	if err := upsertComments(ctx, tx, id, d.Comments); err != nil {
+		return 0, fmt.Errorf("comments: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %v", err)
+	}
+
+	klog.Infof("wrote run %d to %s: %d PRs, %d reviews, %d issues, %d comments", id, path, len(d.PRs), len(d.Reviews), len(d.Issues), len(d.Comments))
+	return id, nil
+}
+
+func insertRun(ctx context.Context, tx *sql.Tx, run Run) (int64, error) {
+	loc := run.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO runs (created_at, command, repos, users, branches, since, until, timezone, incomplete, reason, errors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		repo.Timestamp(time.Now(), loc), run.Command, strings.Join(run.Repos, ","), strings.Join(run.Users, ","),
+		strings.Join(run.Branches, ","), repo.Timestamp(run.Since, loc), repo.Timestamp(run.Until, loc), loc.String(),
+		run.Incomplete, run.Reason, strings.Join(run.Errors, "\n"))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// insertUsers adds every user the summaries refer to
+func insertUsers(ctx context.Context, tx *sql.Tx, d Data) error {
+	logins := []string{}
+	for _, s := range d.PRs {
+		logins = append(logins, s.User)
+	}
+	for _, s := range d.Reviews {
+		logins = append(logins, s.Reviewer, s.PRAuthor)
+	}
+	for _, s := range d.Issues {
+		logins = append(logins, s.Author, s.Closer)
+	}
+	for _, s := range d.Comments {
+		logins = append(logins, s.Commenter, s.IssueAuthor)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO users (login) VALUES (?) ON CONFLICT (login) DO NOTHING")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, l := range logins {
+		if l == "" {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertPRs(ctx context.Context, tx *sql.Tx, run int64, prs []*repo.PRSummary) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO prs (url, run_id, date, user, project, type, title, delta, added, deleted, files_total, description, created_at, merged_at, closed_at, last_activity_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET run_id = excluded.run_id, date = excluded.date, user = excluded.user,
+			project = excluded.project, type = excluded.type, title = excluded.title, delta = excluded.delta,
+			added = excluded.added, deleted = excluded.deleted, files_total = excluded.files_total,
+			description = excluded.description, created_at = excluded.created_at, merged_at = excluded.merged_at,
+			closed_at = excluded.closed_at, last_activity_at = excluded.last_activity_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range prs {
+		if _, err := stmt.ExecContext(ctx, s.URL, run, s.Date, null(s.User), s.Project, s.Type, s.Title, s.Delta, s.Added,
+			s.Deleted, s.FilesTotal, s.Description, s.CreatedAt, s.MergedAt, s.ClosedAt, s.LastActivityAt); err != nil {
+			return fmt.Errorf("%s: %v", s.URL, err)
+		}
+
+		// The files of a pull request may have changed since it was last written
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pr_files WHERE pr_url = ?", s.URL); err != nil {
+			return fmt.Errorf("%s files: %v", s.URL, err)
+		}
+		for _, f := range strings.Split(s.Files, "\n") {
+			if f == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO pr_files (pr_url, path) VALUES (?, ?) ON CONFLICT DO NOTHING", s.URL, f); err != nil {
+				return fmt.Errorf("%s files: %v", s.URL, err)
+			}
+		}
+	}
+	return nil
+}
+
+func upsertReviews(ctx context.Context, tx *sql.Tx, run int64, reviews []*repo.ReviewSummary) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO reviews (url, reviewer, run_id, date, project, pr_author, pr_comments, review_comments, words, title, created_at, merged_at, last_activity_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url, reviewer) DO UPDATE SET run_id = excluded.run_id, date = excluded.date,
+			project = excluded.project, pr_author = excluded.pr_author, pr_comments = excluded.pr_comments,
+			review_comments = excluded.review_comments, words = excluded.words, title = excluded.title,
+			created_at = excluded.created_at, merged_at = excluded.merged_at, last_activity_at = excluded.last_activity_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range reviews {
+		if _, err := stmt.ExecContext(ctx, s.URL, s.Reviewer, run, s.Date, s.Project, null(s.PRAuthor), s.PRComments,
+			s.ReviewComments, s.Words, s.Title, s.CreatedAt, s.MergedAt, s.LastActivityAt); err != nil {
+			return fmt.Errorf("%s: %v", s.URL, err)
+		}
+	}
+	return nil
+}
+
+func upsertIssues(ctx context.Context, tx *sql.Tx, run int64, issues []*repo.IssueSummary) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO issues (url, run_id, date, author, closer, project, type, title, created_at, closed_at, last_activity_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET run_id = excluded.run_id, date = excluded.date, author = excluded.author,
+			closer = excluded.closer, project = excluded.project, type = excluded.type, title = excluded.title,
+			created_at = excluded.created_at, closed_at = excluded.closed_at, last_activity_at = excluded.last_activity_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range issues {
+		if _, err := stmt.ExecContext(ctx, s.URL, run, s.Date, null(s.Author), null(s.Closer), s.Project, s.Type, s.Title,
+			s.CreatedAt, s.ClosedAt, s.LastActivityAt); err != nil {
+			return fmt.Errorf("%s: %v", s.URL, err)
+		}
+	}
+	return nil
+}
+
+func upsertComments(ctx context.Context, tx *sql.Tx, run int64, comments []*repo.CommentSummary) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO comments (url, commenter, run_id, date, project, issue_author, issue_state, comments, words, title, created_at, closed_at, last_activity_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url, commenter) DO UPDATE SET run_id = excluded.run_id, date = excluded.date,
+			project = excluded.project, issue_author = excluded.issue_author, issue_state = excluded.issue_state,
+			comments = excluded.comments, words = excluded.words, title = excluded.title,
+			created_at = excluded.created_at, closed_at = excluded.closed_at, last_activity_at = excluded.last_activity_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range comments {
+		if _, err := stmt.ExecContext(ctx, s.URL, s.Commenter, run, s.Date, s.Project, null(s.IssueAuthor), s.IssueState,
+			s.Comments, s.Words, s.Title, s.CreatedAt, s.ClosedAt, s.LastActivityAt); err != nil {
+			return fmt.Errorf("%s: %v", s.URL, err)
+		}
+	}
+	return nil
+}
+
+// null returns a user reference, or NULL if there is none, as there is no user with an empty login
+func null(login string) interface{} {
+	if login == "" {
+		return nil
+	}
+	return login
+}
@@ -0,0 +1,286 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// Checkpoint records the progress of a run on disk: the results of every completed repository, and how far
+// the listing of the current one got. A run interrupted part way through can be resumed from it by ID.
+type Checkpoint struct {
+	ID   string
+	path string
+
+	mu      sync.Mutex
+	state   checkpointState
+	unsaved int // Pages recorded since the checkpoint was last written
+}
+
+// pagesPerSave is how many pages are recorded before the checkpoint is written. It is also written whenever a
+// listing or repository completes, and when a run stops early.
+const pagesPerSave = 10
+
+// RunSettings are the settings which the results of a run depend on. A run can only be resumed with the same ones.
+type RunSettings struct {
+	Window      string // The window as given, such as "since=now-90d", which is resolved once when the run starts
+	Users       []string
+	Branches    []string
+	IncludeBots bool
+}
+
+// normalize returns the settings with users and branches in a canonical order and case
+func (s RunSettings) normalize() RunSettings {
+	norm := func(vs []string) []string {
+		out := []string{}
+		for _, v := range vs {
+			out = append(out, strings.ToLower(v))
+		}
+		sort.Strings(out)
+		return out
+	}
+	s.Users = norm(s.Users)
+	s.Branches = norm(s.Branches)
+	return s
+}
+
+// String returns the settings as they would be given on the command line
+func (s RunSettings) String() string {
+	return fmt.Sprintf("%s users=%q branches=%q include-bots=%t", s.Window, strings.Join(s.Users, ","), strings.Join(s.Branches, ","), s.IncludeBots)
+}
+
+type checkpointState struct {
+	Since    time.Time
+	Until    time.Time
+	Settings RunSettings
+	Repos    map[string]json.RawMessage // kind/repo -> summaries for a completed repository
+	Pages    map[string]pageState       // listing key -> progress
+}
+
+type pageState struct {
+	Next   int
+	Cursor string `json:",omitempty"` // For listings paged by cursor
+	Found  []int
+}
+
+type checkpointKey struct{}
+
+// NewCheckpoint returns a new checkpoint for a run over the given window with the given settings, stored in dir
+func NewCheckpoint(dir string, since time.Time, until time.Time, settings RunSettings) (*Checkpoint, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+
+	// The random suffix keeps runs started within the same second apart
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("run id: %w", err)
+	}
+
+	id := time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+	cp := &Checkpoint{
+		ID:   id,
+		path: filepath.Join(dir, id+".json"),
+		state: checkpointState{
+			Since:    since,
+			Until:    until,
+			Settings: settings.normalize(),
+			Repos:    map[string]json.RawMessage{},
+			Pages:    map[string]pageState{},
+		},
+	}
+
+	return cp, cp.save()
+}
+
+// LoadCheckpoint returns the checkpoint for an earlier run from dir. It fails if the run was started with other
+// settings, as the results it holds would not match them.
+func LoadCheckpoint(dir string, id string, settings RunSettings) (*Checkpoint, error) {
+	cp := &Checkpoint{ID: id, path: filepath.Join(dir, id+".json")}
+	b, err := os.ReadFile(cp.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no checkpoint for run %q in %s", id, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &cp.state); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", cp.path, err)
+	}
+
+	if got, want := settings.normalize(), cp.state.Settings; !reflect.DeepEqual(got, want) {
+		return nil, fmt.Errorf("run %s was started with %s, not %s. Start a new run instead", id, want, got)
+	}
+
+	if cp.state.Repos == nil {
+		cp.state.Repos = map[string]json.RawMessage{}
+	}
+	if cp.state.Pages == nil {
+		cp.state.Pages = map[string]pageState{}
+	}
+
+	klog.Infof("resuming run %s: %d repositories complete", id, len(cp.state.Repos))
+	return cp, nil
+}
+
+// Window returns the time window the run was started with
+func (cp *Checkpoint) Window() (since time.Time, until time.Time) {
+	return cp.state.Since, cp.state.Until
+}
+
+// Remove deletes the checkpoint, once the run has completed
+func (cp *Checkpoint) Remove() error {
+	return os.Remove(cp.path)
+}
+
+// ResumePage implements repo.PageCheckpoint
+func (cp *Checkpoint) ResumePage(key string) (int, []int, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	ps, ok := cp.state.Pages[key]
+	return ps.Next, ps.Found, ok
+}
+
+// SavePage implements repo.PageCheckpoint
+func (cp *Checkpoint) SavePage(key string, next int, found []int) error {
+	return cp.savePage(key, pageState{Next: next, Found: found}, next == 0)
+}
+
+// ResumeCursor implements repo.PageCheckpoint
+func (cp *Checkpoint) ResumeCursor(key string) (string, []int, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	ps, ok := cp.state.Pages[key]
+	return ps.Cursor, ps.Found, ok
+}
+
+// SaveCursor implements repo.PageCheckpoint
+func (cp *Checkpoint) SaveCursor(key string, next string, found []int) error {
+	return cp.savePage(key, pageState{Cursor: next, Found: found}, next == "")
+}
+
+// savePage records the progress of a listing, writing the checkpoint once the listing is complete or enough pages
+// have been recorded since it was last written
+func (cp *Checkpoint) savePage(key string, ps pageState, complete bool) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.state.Pages[key] = ps
+	cp.unsaved++
+	if !complete && cp.unsaved < pagesPerSave {
+		return nil
+	}
+	return cp.save()
+}
+
+// Save writes the checkpoint, including pages recorded since it was last written. Call it when a run stops early.
+func (cp *Checkpoint) Save() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return cp.save()
+}
+
+// repoDone decodes the results of a completed repository into out, returning false if it has not completed
+func (cp *Checkpoint) repoDone(kind string, r string, out interface{}) bool {
+	if cp == nil {
+		return false
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	b, ok := cp.state.Repos[kind+"/"+r]
+	if !ok {
+		return false
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		klog.Errorf("checkpoint for %s/%s is unreadable, fetching again: %v", kind, r, err)
+		return false
+	}
+
+	klog.Infof("%s for %s already completed in run %s", kind, r, cp.ID)
+	return true
+}
+
+// finishRepo records the results of a completed repository
+func (cp *Checkpoint) finishRepo(kind string, r string, results interface{}) error {
+	if cp == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.state.Repos[kind+"/"+r] = b
+	return cp.save()
+}
+
+// save writes the checkpoint atomically, so that an interruption never leaves it half written. cp.mu must be held,
+// except while the checkpoint is being created.
+func (cp *Checkpoint) save() error {
+	b, err := json.Marshal(cp.state)
+	if err != nil {
+		return err
+	}
+
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return err
+	}
+	cp.unsaved = 0
+	return nil
+}
+
+// WithCheckpoint returns a context in which the summary functions record their progress to cp,
+// skipping repositories that it shows as complete.
+func WithCheckpoint(ctx context.Context, cp *Checkpoint) context.Context {
+	return context.WithValue(repo.WithPageCheckpoint(ctx, cp), checkpointKey{}, cp)
+}
+
+// checkpoint returns the checkpoint for a context, if any
+func checkpoint(ctx context.Context) *Checkpoint {
+	cp, _ := ctx.Value(checkpointKey{}).(*Checkpoint)
+	return cp
+}
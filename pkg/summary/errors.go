@@ -0,0 +1,73 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// RepoError is a failure to collect one kind of data for a repository
+type RepoError struct {
+	Repo  string
+	Kind  string // prs, reviews, issues or comments
+	Error string
+}
+
+// Errors collects the failures of individual repositories, so that a run can continue with the rest
+type Errors struct {
+	mu   sync.Mutex
+	list []RepoError
+}
+
+type errorsKey struct{}
+
+// List returns the failures collected so far
+func (e *Errors) List() []RepoError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]RepoError{}, e.list...)
+}
+
+// failRepo records the failure of a repository, returning false if the run is not keeping going after failures
+func (e *Errors) failRepo(ctx context.Context, kind string, r string, err error) bool {
+	// Interruptions end the run rather than a single repository
+	if e == nil || ctx.Err() != nil {
+		return false
+	}
+
+	klog.Errorf("%s for %s failed, continuing with the remaining repositories: %v", kind, r, err)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.list = append(e.list, RepoError{Repo: r, Kind: kind, Error: err.Error()})
+	return true
+}
+
+// WithErrors returns a context in which the summary functions record the failure of a repository to e and continue
+// with the remaining repositories, rather than returning an error.
+func WithErrors(ctx context.Context, e *Errors) context.Context {
+	return context.WithValue(ctx, errorsKey{}, e)
+}
+
+// ErrorsFrom returns the failures collector for a context, if any
+func ErrorsFrom(ctx context.Context) *Errors {
+	e, _ := ctx.Value(errorsKey{}).(*Errors)
+	return e
+}
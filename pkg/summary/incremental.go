@@ -0,0 +1,387 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+	"github.com/google/pullsheet/pkg/ghcache"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+// IncrementalPulls updates prev, the pull request summaries from an earlier run described by sync, fetching only pull
+// requests updated since each repository was last synced. Unless sync shows that prev covers the window with the same
+// users and branches, the whole window is fetched. The sync state of the updated summaries is returned with them, and
+// its watermarks must be saved once they are stored. On error, the summaries merged so far are returned along with it.
+func IncrementalPulls(ctx context.Context, c *client.Client, prev []*repo.PRSummary, sync *Sync, repos []string, users []string, branches []string, since time.Time, until time.Time) ([]*repo.PRSummary, *Sync, error) {
+	sync, ok := sync.next("prs", filterKey(users, branches), repos, since, until)
+	result := prev
+	if !ok {
+		result = nil
+	}
+	url := func(s *repo.PRSummary) string { return s.URL }
+	date := func(s *repo.PRSummary) string { return s.Date }
+	errs := ErrorsFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("prs", len(repos))
+	c = prog.client(c)
+
+	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, err
+		}
+
+		prog.startRepo(r)
+		org, project := repo.ParseURL(r)
+		start := time.Now()
+		updated := sync.updatedSince(c, r, since)
+
+		prs, err := repo.MergedPullsUpdated(ctx, c, org, project, since, until, updated, users, branches)
+		if err != nil && errs.failRepo(ctx, "prs", r, err) {
+			prog.finishRepo()
+			continue
+		}
+		if err != nil {
+			err = fmt.Errorf("list: %v", err)
+		}
+
+		// If listing failed, summarize the pull requests whose files are already at hand
+		prFiles := map[*github.PullRequest][]github.CommitFile{}
+		if ferr := addFiles(ctx, c, org, project, prs, prFiles); ferr != nil && err == nil {
+			if errs.failRepo(ctx, "prs", r, ferr) {
+				prog.finishRepo()
+				continue
+			}
+			err = ferr
+		}
+
+		sum, serr := repo.PullSummary(prFiles, since, until, c.Location)
+		if serr != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, fmt.Errorf("pull summary failed: %v", serr)
+		}
+
+		touched := []string{}
+		for _, s := range sum {
+			touched = append(touched, s.URL)
+		}
+
+		result = merge(result, sum, touched, url)
+		if err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, err
+		}
+		sync.synced(r, start)
+		prog.finishRepo()
+	}
+
+	return current(result, repos, since, until, c.Location, url, date), sync, nil
+}
+
+// IncrementalReviews updates prev, the review summaries from an earlier run described by sync, fetching only
+// pull requests updated since each repository was last synced. Unless sync shows that prev covers the window with the
+// same users, the whole window is fetched. The sync state of the updated summaries is returned with them, and its
+// watermarks must be saved once they are stored. On error, the summaries merged so far are returned along with it.
+func IncrementalReviews(ctx context.Context, c *client.Client, prev []*repo.ReviewSummary, sync *Sync, repos []string, users []string, since time.Time, until time.Time) ([]*repo.ReviewSummary, *Sync, error) {
+	sync, ok := sync.next("reviews", filterKey(users, nil), repos, since, until)
+	result := prev
+	if !ok {
+		result = nil
+	}
+	url := func(s *repo.ReviewSummary) string { return s.URL }
+	date := func(s *repo.ReviewSummary) string { return s.Date }
+	errs := ErrorsFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("reviews", len(repos))
+	c = prog.client(c)
+
+	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, err
+		}
+
+		prog.startRepo(r)
+		org, project := repo.ParseURL(r)
+		start := time.Now()
+		updated := sync.updatedSince(c, r, since)
+
+		rs, touched, err := repo.MergedReviewsUpdated(ctx, c, org, project, since, until, updated, users)
+		if err != nil && errs.failRepo(ctx, "reviews", r, err) {
+			prog.finishRepo()
+			continue
+		}
+
+		result = merge(result, rs, touched, url)
+		if err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, fmt.Errorf("merged pulls: %v", err)
+		}
+		sync.synced(r, start)
+		prog.finishRepo()
+	}
+
+	return current(result, repos, since, until, c.Location, url, date), sync, nil
+}
+
+// IncrementalIssues updates prev, the issue summaries from an earlier run described by sync, fetching only
+// issues updated since each repository was last synced. Unless sync shows that prev covers the window with the
+// same users, the whole window is fetched. The sync state of the updated summaries is returned with them, and its
+// watermarks must be saved once they are stored. On error, the summaries merged so far are returned along with it.
+func IncrementalIssues(ctx context.Context, c *client.Client, prev []*repo.IssueSummary, sync *Sync, repos []string, users []string, since time.Time, until time.Time) ([]*repo.IssueSummary, *Sync, error) {
+	sync, ok := sync.next("issues", filterKey(users, nil), repos, since, until)
+	result := prev
+	if !ok {
+		result = nil
+	}
+	url := func(s *repo.IssueSummary) string { return s.URL }
+	date := func(s *repo.IssueSummary) string { return s.Date }
+	errs := ErrorsFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("issues", len(repos))
+	c = prog.client(c)
+
+	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, err
+		}
+
+		prog.startRepo(r)
+		org, project := repo.ParseURL(r)
+		start := time.Now()
+		updated := sync.updatedSince(c, r, since)
+
+		is, touched, err := repo.ClosedIssuesUpdated(ctx, c, org, project, since, until, updated, users)
+		if err != nil && errs.failRepo(ctx, "issues", r, err) {
+			prog.finishRepo()
+			continue
+		}
+
+		result = merge(result, is, touched, url)
+		if err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, fmt.Errorf("closed issues: %v", err)
+		}
+		sync.synced(r, start)
+		prog.finishRepo()
+	}
+
+	return current(result, repos, since, until, c.Location, url, date), sync, nil
+}
+
+// IncrementalComments updates prev, the comment summaries from an earlier run described by sync, fetching only
+// issues updated since each repository was last synced. Unless sync shows that prev covers the window with the
+// same users, the whole window is fetched. The sync state of the updated summaries is returned with them, and its
+// watermarks must be saved once they are stored. On error, the summaries merged so far are returned along with it.
+func IncrementalComments(ctx context.Context, c *client.Client, prev []*repo.CommentSummary, sync *Sync, repos []string, users []string, since time.Time, until time.Time) ([]*repo.CommentSummary, *Sync, error) {
+	sync, ok := sync.next("comments", filterKey(users, nil), repos, since, until)
+	result := prev
+	if !ok {
+		result = nil
+	}
+	url := func(s *repo.CommentSummary) string { return s.URL }
+	date := func(s *repo.CommentSummary) string { return s.Date }
+	errs := ErrorsFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("comments", len(repos))
+	c = prog.client(c)
+
+	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, err
+		}
+
+		prog.startRepo(r)
+		org, project := repo.ParseURL(r)
+		start := time.Now()
+		updated := sync.updatedSince(c, r, since)
+
+		cs, touched, err := repo.IssueCommentsUpdated(ctx, c, org, project, since, until, updated, users)
+		if err != nil && errs.failRepo(ctx, "comments", r, err) {
+			prog.finishRepo()
+			continue
+		}
+
+		result = merge(result, cs, touched, url)
+		if err != nil {
+			return current(result, repos, since, until, c.Location, url, date), sync, fmt.Errorf("issue comments: %v", err)
+		}
+		sync.synced(r, start)
+		prog.finishRepo()
+	}
+
+	return current(result, repos, since, until, c.Location, url, date), sync, nil
+}
+
+// Sync describes what incremental results cover, so that a later run only builds on results covering its window.
+// When each repository was last synced is kept in the persist backend, as watermarks identified by ID.
+type Sync struct {
+	Since  time.Time // Start of the window the results were collected for
+	Until  time.Time // End of the window the results were collected for
+	Filter string    // Key of the user and branch filters the results were collected with
+	ID     int64     // Identifies the watermarks of these results, apart from those of other results
+	Repos  []string  // Repositories whose results were collected since their watermark, as org/project
+
+	pending map[string]time.Time // Watermarks to save, by org/project
+}
+
+// next returns the sync state for a run over a window, and whether the results described by s can be built on. They
+// can if they were collected with the same filters over a window starting no later than since. Repositories are
+// synced up to the end of their window at most, so anything dated after it is fetched again however old the window.
+// Repositories left out of the run are dropped, so that their results are fetched in full if they are added back.
+func (s *Sync) next(kind string, filter string, repos []string, since time.Time, until time.Time) (*Sync, bool) {
+	n := &Sync{Since: since, Until: until, Filter: filter, ID: rand.Int63(), Repos: []string{}, pending: map[string]time.Time{}}
+	switch {
+	case s == nil || s.ID == 0:
+		klog.Infof("no earlier %s to build on, fetching everything since %s", kind, since)
+		return n, false
+	case s.Filter != filter:
+		klog.Infof("earlier %s were collected with other users or branches, fetching everything since %s", kind, since)
+		return n, false
+	case since.Before(s.Since):
+		klog.Infof("earlier %s only cover %s to %s, fetching everything since %s", kind, s.Since, s.Until, since)
+		return n, false
+	}
+
+	n.ID = s.ID
+	for _, r := range s.Repos {
+		if slices.Contains(repos, r) {
+			n.Repos = append(n.Repos, r)
+		}
+	}
+	return n, true
+}
+
+// updatedSince returns the earliest update time worth fetching for a repository: when it was last synced if that
+// falls within the window, otherwise the start of the window.
+func (s *Sync) updatedSince(c *client.Client, r string, since time.Time) time.Time {
+	if !slices.Contains(s.Repos, r) {
+		return since
+	}
+
+	org, project := repo.ParseURL(r)
+	if wm := ghcache.SyncGet(c.Cache, org, project, s.ID); wm.After(since) {
+		klog.Infof("%s last synced at %s, fetching updates since then", r, wm)
+		return wm
+	}
+	return since
+}
+
+// synced records that a repository was synced at t, or up to the end of the window if that is earlier
+func (s *Sync) synced(r string, t time.Time) {
+	if s.Until.Before(t) {
+		t = s.Until
+	}
+	s.pending[r] = t
+	if !slices.Contains(s.Repos, r) {
+		s.Repos = append(s.Repos, r)
+	}
+}
+
+// Save stores the watermarks of the repositories synced by a run in the persist backend. Call it once the results
+// s describes are stored, so that no later run builds on a watermark for results that were lost.
+func (s *Sync) Save(c *client.Client) error {
+	if s == nil {
+		return nil
+	}
+
+	for r, t := range s.pending {
+		org, project := repo.ParseURL(r)
+		if err := ghcache.SyncSet(c.Cache, org, project, s.ID, t); err != nil {
+			return fmt.Errorf("watermark for %s: %v", r, err)
+		}
+		delete(s.pending, r)
+	}
+	return nil
+}
+
+// filterKey returns a short, stable key for a set of user and branch filters
+func filterKey(users []string, branches []string) string {
+	us := append([]string{}, users...)
+	bs := append([]string{}, branches...)
+	sort.Strings(us)
+	sort.Strings(bs)
+
+	h := sha256.Sum256([]byte(strings.ToLower(strings.Join(us, ",") + "|" + strings.Join(bs, ","))))
+	return fmt.Sprintf("%x", h[:6])
+}
+
+// merge drops previous summaries for every touched URL, as they have been recomputed, and appends the updated ones
+func merge[T any](prev []T, updated []T, touched []string, url func(T) string) []T {
+	replaced := map[string]bool{}
+	for _, u := range touched {
+		replaced[u] = true
+	}
+
+	result := []T{}
+	for _, s := range prev {
+		if !replaced[url(s)] {
+			result = append(result, s)
+		}
+	}
+
+	return append(result, updated...)
+}
+
+// current drops summaries of repositories left out of the run, and those dated outside of its window, such as
+// previous results that the window has moved past. Summaries are dated in loc.
+func current[T any](sums []T, repos []string, since time.Time, until time.Time, loc *time.Location, link func(T) string, date func(T) string) []T {
+	paths := []string{}
+	for _, r := range repos {
+		org, project := repo.ParseURL(r)
+		paths = append(paths, strings.ToLower("/"+org+"/"+project+"/"))
+	}
+	inRun := func(raw string) bool {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return false
+		}
+		for _, p := range paths {
+			if strings.HasPrefix(strings.ToLower(u.Path), p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	from := repo.Date(since, loc)
+	to := repo.Date(until, loc)
+
+	result := []T{}
+	for _, s := range sums {
+		if !inRun(link(s)) {
+			continue
+		}
+
+		d := date(s)
+		if d < from || d > to {
+			continue
+		}
+		result = append(result, s)
+	}
+
+	return result
+}
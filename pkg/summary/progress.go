@@ -0,0 +1,269 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/google/triage-party/pkg/persist"
+	"k8s.io/klog/v2"
+
+	"github.com/google/pullsheet/pkg/client"
+)
+
+// ProgressStats is a snapshot of how far a run has got
+type ProgressStats struct {
+	Kind          string // Kind of data being collected: prs, reviews, issues or comments
+	Repo          string // Repository being collected
+	ReposDone     int
+	ReposTotal    int
+	Pages         int // Responses received from GitHub
+	NotModified   int // Responses answered from the HTTP cache after a conditional request
+	CacheHits     int
+	CacheMisses   int
+	RateRemaining int // Remaining GitHub rate limit, or -1 if unknown
+	RateLimit     int
+	RateReset     time.Time
+	Elapsed       time.Duration
+}
+
+// Progress tracks how far a run has got. It is safe for concurrent use.
+type Progress struct {
+	mu      sync.Mutex
+	stats   ProgressStats
+	started time.Time
+}
+
+type progressKey struct{}
+
+// NewProgress returns a new progress tracker
+func NewProgress() *Progress {
+	return &Progress{stats: ProgressStats{RateRemaining: -1}, started: time.Now()}
+}
+
+// WithProgress returns a context in which the summary functions record their progress to p
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progress returns the progress tracker for a context, if any
+func progress(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// Stats returns a snapshot of the progress so far
+func (p *Progress) Stats() ProgressStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.stats
+	s.Elapsed = time.Since(p.started)
+	return s
+}
+
+// String returns a one line description of the progress so far
+func (s ProgressStats) String() string {
+	parts := []string{}
+	if s.Kind != "" {
+		at := ""
+		if s.Repo != "" {
+			at = ", at " + s.Repo
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d/%d repos%s", s.Kind, s.ReposDone, s.ReposTotal, at))
+	}
+
+	parts = append(parts, fmt.Sprintf("%d pages (%d not modified)", s.Pages, s.NotModified))
+
+	if lookups := s.CacheHits + s.CacheMisses; lookups > 0 {
+		parts = append(parts, fmt.Sprintf("%d%% cache hits", s.CacheHits*100/lookups))
+	}
+
+	if s.RateRemaining >= 0 {
+		parts = append(parts, fmt.Sprintf("rate limit %d/%d, resets %s", s.RateRemaining, s.RateLimit, s.RateReset.Format("15:04")))
+	}
+
+	parts = append(parts, s.Elapsed.Round(time.Second).String())
+	return strings.Join(parts, " | ")
+}
+
+// Start reports progress to f until the returned function is called: as a line redrawn in place if f is a
+// terminal, and otherwise as a log line every interval.
+func (p *Progress) Start(f *os.File, interval time.Duration) func() {
+	tty := false
+	if fi, err := f.Stat(); err == nil {
+		tty = fi.Mode()&os.ModeCharDevice != 0
+	}
+	if tty {
+		interval = 250 * time.Millisecond
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if tty {
+					fmt.Fprintf(f, "\r\033[K%s", p.Stats())
+				} else {
+					klog.Infof("progress: %s", p.Stats())
+				}
+			case <-done:
+				if tty {
+					fmt.Fprintf(f, "\r\033[K%s\n", p.Stats())
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// startKind records the start of collecting a kind of data over repos
+func (p *Progress) startKind(kind string, repos int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Kind = kind
+	p.stats.Repo = ""
+	p.stats.ReposDone = 0
+	p.stats.ReposTotal = repos
+}
+
+// startRepo records the start of collecting data for a repository
+func (p *Progress) startRepo(r string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Repo = r
+}
+
+// finishRepo records that a repository has been collected
+func (p *Progress) finishRepo() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Repo = ""
+	p.stats.ReposDone++
+}
+
+// client returns a copy of c whose cache lookups and HTTP responses are counted
+func (p *Progress) client(c *client.Client) *client.Client {
+	if p == nil {
+		return c
+	}
+
+	base := http.DefaultTransport
+	if c.HTTPClient != nil && c.HTTPClient.Transport != nil {
+		base = c.HTTPClient.Transport
+	}
+
+	pc := *c
+	pc.Cache = &progressCacher{Cacher: c.Cache, p: p}
+	pc.HTTPClient = &http.Client{Transport: &progressTransport{base: base, p: p}}
+	pc.GitHubClient = github.NewClient(pc.HTTPClient)
+	if c.GitHubClient != nil {
+		pc.GitHubClient.BaseURL = c.GitHubClient.BaseURL
+		pc.GitHubClient.UploadURL = c.GitHubClient.UploadURL
+	}
+	return &pc
+}
+
+// progressCacher counts cache hits and misses
+type progressCacher struct {
+	persist.Cacher
+	p *Progress
+}
+
+// Get implements persist.Cacher
+func (c *progressCacher) Get(key string, t time.Time) *persist.Blob {
+	b := c.Cacher.Get(key, t)
+
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+
+	if b != nil {
+		c.p.stats.CacheHits++
+	} else {
+		c.p.stats.CacheMisses++
+	}
+	return b
+}
+
+// progressTransport counts responses and tracks the rate limit they report
+type progressTransport struct {
+	base http.RoundTripper
+	p    *Progress
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *progressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.p.mu.Lock()
+	defer t.p.mu.Unlock()
+
+	t.p.stats.Pages++
+	if resp.Header.Get("X-From-Cache") != "" {
+		t.p.stats.NotModified++
+	}
+
+	// The search API has a separate, much smaller, rate limit
+	if resp.Header.Get("X-Ratelimit-Resource") == "search" {
+		return resp, nil
+	}
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Remaining")); err == nil {
+		t.p.stats.RateRemaining = remaining
+		t.p.stats.RateLimit, _ = strconv.Atoi(resp.Header.Get("X-Ratelimit-Limit"))
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			t.p.stats.RateReset = time.Unix(reset, 0)
+		}
+	}
+
+	return resp, nil
+}
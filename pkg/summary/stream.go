@@ -0,0 +1,71 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Stream receives the summaries of each repository as soon as they are collected, so that they can be written
+// while the run continues. It is safe for concurrent use.
+type Stream struct {
+	mu   sync.Mutex
+	send func(kind string, summaries interface{}) error
+}
+
+type streamKey struct{}
+
+// NewStream returns a stream which passes the summaries of each repository, such as a []*repo.PRSummary, to send
+func NewStream(send func(kind string, summaries interface{}) error) *Stream {
+	return &Stream{send: send}
+}
+
+// WithStream returns a context in which the summary functions send the summaries of each repository to s once it
+// is complete. Incremental summaries are not streamed, as they are merged with earlier results at the end.
+func WithStream(ctx context.Context, s *Stream) context.Context {
+	return context.WithValue(ctx, streamKey{}, s)
+}
+
+// StreamFrom returns the stream for a context, if any
+func StreamFrom(ctx context.Context) *Stream {
+	s, _ := ctx.Value(streamKey{}).(*Stream)
+	return s
+}
+
+// emit sends the summaries of a repository
+func (s *Stream) emit(kind string, summaries interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.send(kind, summaries)
+}
+
+// partial sends the summaries of a repository which were collected before the run was interrupted. They are not
+// sent for other failures, which end the run without results.
+func (s *Stream) partial(ctx context.Context, kind string, summaries interface{}) {
+	if ctx.Err() == nil {
+		return
+	}
+	if err := s.emit(kind, summaries); err != nil {
+		klog.Errorf("stream %s: %v", kind, err)
+	}
+}
@@ -27,81 +27,256 @@ import (
 )
 
 // Pulls returns a summary of pull requests for the specified repositories, users, and branches.
+// On error, the summaries gathered so far are returned along with it, unless the context collects the
+// failures of repositories with WithErrors.
 func Pulls(ctx context.Context, c *client.Client, repos []string, users []string, branches []string, since time.Time, until time.Time) ([]*repo.PRSummary, error) {
-	prFiles := map[*github.PullRequest][]github.CommitFile{}
+	sum := []*repo.PRSummary{}
+	cp := checkpoint(ctx)
+	errs := ErrorsFrom(ctx)
+	st := StreamFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("prs", len(repos))
+	c = prog.client(c)
 
 	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+
+		var rs []*repo.PRSummary
+		if cp.repoDone("prs", r, &rs) {
+			if err := st.emit("prs", rs); err != nil {
+				return sum, fmt.Errorf("stream: %v", err)
+			}
+			sum = append(sum, rs...)
+			prog.finishRepo()
+			continue
+		}
+
+		prog.startRepo(r)
 		org, project := repo.ParseURL(r)
 
 		prs, err := repo.MergedPulls(ctx, c, org, project, since, until, users, branches)
-		if err != nil {
-			return nil, fmt.Errorf("list: %v", err)
+		if err != nil && errs.failRepo(ctx, "prs", r, err) {
+			prog.finishRepo()
+			continue
+		}
+		if err != nil && ctx.Err() == nil {
+			return sum, fmt.Errorf("list: %v", err)
 		}
 
-		for _, pr := range prs {
-			files, err := repo.FilteredFiles(ctx, c, pr.GetMergedAt(), org, project, pr.GetNumber())
-			if err != nil {
-				return nil, fmt.Errorf("filtered files: %v", err)
+		// If the run was interrupted, summarize the pull requests whose files are already at hand
+		prFiles := map[*github.PullRequest][]github.CommitFile{}
+		if ferr := addFiles(ctx, c, org, project, prs, prFiles); ferr != nil && err == nil {
+			if errs.failRepo(ctx, "prs", r, ferr) {
+				prog.finishRepo()
+				continue
 			}
-			klog.Errorf("%s files: %v", pr, files)
+			err = ferr
+		}
 
-			prFiles[pr] = []github.CommitFile{}
+		rs, serr := repo.PullSummary(prFiles, since, until, c.Location)
+		if serr != nil {
+			return sum, fmt.Errorf("pull summary failed: %v", serr)
+		}
 
-			for _, f := range files {
-				prFiles[pr] = append(prFiles[pr], *f)
-			}
+		if err != nil {
+			st.partial(ctx, "prs", rs)
+			return append(sum, rs...), err
 		}
-	}
 
-	sum, err := repo.PullSummary(prFiles, since, until)
-	if err != nil {
-		return nil, fmt.Errorf("pull summary failed: %v", err)
+		if err := cp.finishRepo("prs", r, rs); err != nil {
+			return sum, fmt.Errorf("checkpoint: %v", err)
+		}
+		if err := st.emit("prs", rs); err != nil {
+			return sum, fmt.Errorf("stream: %v", err)
+		}
+		sum = append(sum, rs...)
+		prog.finishRepo()
 	}
 
 	return sum, nil
 }
 
+// addFiles adds the files changed by each pull request to prFiles. If the context is done, pull requests
+// whose files are not cached are left out, and an error is returned once the rest have been added.
+func addFiles(ctx context.Context, c *client.Client, org string, project string, prs []*github.PullRequest, prFiles map[*github.PullRequest][]github.CommitFile) error {
+	var interrupted error
+	for _, pr := range prs {
+		files, err := repo.FilteredFiles(ctx, c, pr.GetUpdatedAt(), org, project, pr.GetNumber())
+		if err != nil && ctx.Err() != nil {
+			interrupted = fmt.Errorf("filtered files: %v", err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("filtered files: %v", err)
+		}
+		klog.Errorf("%s files: %v", pr, files)
+
+		prFiles[pr] = []github.CommitFile{}
+
+		for _, f := range files {
+			prFiles[pr] = append(prFiles[pr], *f)
+		}
+	}
+	return interrupted
+}
+
 // Reviews returns a summary of reviews for the specified repositories and users.
+// On error, the summaries gathered so far are returned along with it, unless the context collects the
+// failures of repositories with WithErrors.
 func Reviews(ctx context.Context, c *client.Client, repos []string, users []string, since time.Time, until time.Time) ([]*repo.ReviewSummary, error) {
 	rs := []*repo.ReviewSummary{}
+	cp := checkpoint(ctx)
+	errs := ErrorsFrom(ctx)
+	st := StreamFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("reviews", len(repos))
+	c = prog.client(c)
+
 	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return rs, err
+		}
+
+		var rrs []*repo.ReviewSummary
+		if cp.repoDone("reviews", r, &rrs) {
+			if err := st.emit("reviews", rrs); err != nil {
+				return rs, fmt.Errorf("stream: %v", err)
+			}
+			rs = append(rs, rrs...)
+			prog.finishRepo()
+			continue
+		}
+
+		prog.startRepo(r)
 		org, project := repo.ParseURL(r)
 		rrs, err := repo.MergedReviews(ctx, c, org, project, since, until, users)
+		if err != nil && errs.failRepo(ctx, "reviews", r, err) {
+			prog.finishRepo()
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("merged pulls: %v", err)
+			st.partial(ctx, "reviews", rrs)
+			return append(rs, rrs...), fmt.Errorf("merged pulls: %v", err)
+		}
+
+		if err := cp.finishRepo("reviews", r, rrs); err != nil {
+			return rs, fmt.Errorf("checkpoint: %v", err)
+		}
+		if err := st.emit("reviews", rrs); err != nil {
+			return rs, fmt.Errorf("stream: %v", err)
 		}
 		rs = append(rs, rrs...)
+		prog.finishRepo()
 	}
 
 	return rs, nil
 }
 
 // Issues returns a summary of issues for the specified repositories and users.
+// On error, the summaries gathered so far are returned along with it, unless the context collects the
+// failures of repositories with WithErrors.
 func Issues(ctx context.Context, c *client.Client, repos []string, users []string, since time.Time, until time.Time) ([]*repo.IssueSummary, error) {
 	rs := []*repo.IssueSummary{}
+	cp := checkpoint(ctx)
+	errs := ErrorsFrom(ctx)
+	st := StreamFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("issues", len(repos))
+	c = prog.client(c)
+
 	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return rs, err
+		}
+
+		var rrs []*repo.IssueSummary
+		if cp.repoDone("issues", r, &rrs) {
+			if err := st.emit("issues", rrs); err != nil {
+				return rs, fmt.Errorf("stream: %v", err)
+			}
+			rs = append(rs, rrs...)
+			prog.finishRepo()
+			continue
+		}
+
+		prog.startRepo(r)
 		org, project := repo.ParseURL(r)
 		rrs, err := repo.ClosedIssues(ctx, c, org, project, since, until, users)
+		if err != nil && errs.failRepo(ctx, "issues", r, err) {
+			prog.finishRepo()
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("merged pulls: %v", err)
+			st.partial(ctx, "issues", rrs)
+			return append(rs, rrs...), fmt.Errorf("merged pulls: %v", err)
+		}
+
+		if err := cp.finishRepo("issues", r, rrs); err != nil {
+			return rs, fmt.Errorf("checkpoint: %v", err)
+		}
+		if err := st.emit("issues", rrs); err != nil {
+			return rs, fmt.Errorf("stream: %v", err)
 		}
 		rs = append(rs, rrs...)
+		prog.finishRepo()
 	}
 
 	return rs, nil
 }
 
 // Comments returns a summary of comments for the specified repositories and users.
+// On error, the summaries gathered so far are returned along with it, unless the context collects the
+// failures of repositories with WithErrors.
 func Comments(ctx context.Context, c *client.Client, repos []string, users []string, since time.Time, until time.Time) ([]*repo.CommentSummary, error) {
 	rs := []*repo.CommentSummary{}
+	cp := checkpoint(ctx)
+	errs := ErrorsFrom(ctx)
+	st := StreamFrom(ctx)
+
+	prog := progress(ctx)
+	prog.startKind("comments", len(repos))
+	c = prog.client(c)
+
 	for _, r := range repos {
+		if err := ctx.Err(); err != nil {
+			return rs, err
+		}
+
+		var rrs []*repo.CommentSummary
+		if cp.repoDone("comments", r, &rrs) {
+			if err := st.emit("comments", rrs); err != nil {
+				return rs, fmt.Errorf("stream: %v", err)
+			}
+			rs = append(rs, rrs...)
+			prog.finishRepo()
+			continue
+		}
+
+		prog.startRepo(r)
 		org, project := repo.ParseURL(r)
 		rrs, err := repo.IssueComments(ctx, c, org, project, since, until, users)
+		if err != nil && errs.failRepo(ctx, "comments", r, err) {
+			prog.finishRepo()
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("merged pulls: %v", err)
+			st.partial(ctx, "comments", rrs)
+			return append(rs, rrs...), fmt.Errorf("merged pulls: %v", err)
 		}
 
+		if err := cp.finishRepo("comments", r, rrs); err != nil {
+			return rs, fmt.Errorf("checkpoint: %v", err)
+		}
+		if err := st.emit("comments", rrs); err != nil {
+			return rs, fmt.Errorf("stream: %v", err)
+		}
 		rs = append(rs, rrs...)
+		prog.finishRepo()
 	}
 
 	return rs, nil
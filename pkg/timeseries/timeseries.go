@@ -0,0 +1,179 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeseries groups summaries into buckets of time, such as days, weeks or months.
+package timeseries
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/pullsheet/pkg/period"
+	"github.com/google/pullsheet/pkg/repo"
+)
+
+const dateForm = "2006-01-02"
+
+// Bucket is a period of time and the summaries dated within it
+type Bucket struct {
+	period.Period
+	PRs      []*repo.PRSummary
+	Reviews  []*repo.ReviewSummary
+	Issues   []*repo.IssueSummary
+	Comments []*repo.CommentSummary
+}
+
+// Point is the activity of a user in a bucket
+type Point struct {
+	Bucket         string // Name of the bucket, such as 2021-03-01, 2021-W09 or 2021-03
+	Since          string // First day of the bucket
+	User           string
+	PRs            int // Pull requests merged
+	Delta          int // Lines changed by pull requests merged
+	Reviews        int // Merged pull requests reviewed
+	ReviewComments int
+	ReviewWords    int
+	IssuesClosed   int
+	Comments       int // Issue comments
+	CommentWords   int
+}
+
+// Split groups summaries, dated in loc, into buckets of a unit: day, week or month. Buckets are returned oldest
+// first, including those without activity between the first and last, so that series are continuous.
+func Split(unit string, loc *time.Location, prs []*repo.PRSummary, reviews []*repo.ReviewSummary, issues []*repo.IssueSummary, comments []*repo.CommentSummary) ([]*Bucket, error) {
+	if _, err := period.Start(time.Time{}, unit); err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	byName := map[string]*Bucket{}
+	bucket := func(date string) (*Bucket, error) {
+		t, err := time.ParseInLocation(dateForm, date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("date %q: %v", date, err)
+		}
+		p, err := period.Start(t, unit)
+		if err != nil {
+			return nil, err
+		}
+		if byName[p.Name] == nil {
+			byName[p.Name] = &Bucket{Period: p}
+		}
+		return byName[p.Name], nil
+	}
+
+	for _, s := range prs {
+		b, err := bucket(s.Date)
+		if err != nil {
+			return nil, err
+		}
+		b.PRs = append(b.PRs, s)
+	}
+	for _, s := range reviews {
+		b, err := bucket(s.Date)
+		if err != nil {
+			return nil, err
+		}
+		b.Reviews = append(b.Reviews, s)
+	}
+	for _, s := range issues {
+		b, err := bucket(s.Date)
+		if err != nil {
+			return nil, err
+		}
+		b.Issues = append(b.Issues, s)
+	}
+	for _, s := range comments {
+		b, err := bucket(s.Date)
+		if err != nil {
+			return nil, err
+		}
+		b.Comments = append(b.Comments, s)
+	}
+
+	if len(byName) == 0 {
+		return nil, nil
+	}
+
+	first, last := time.Time{}, time.Time{}
+	for _, b := range byName {
+		if first.IsZero() || b.Since.Before(first) {
+			first = b.Since
+		}
+		if b.Since.After(last) {
+			last = b.Since
+		}
+	}
+
+	ps, err := period.Range(first, last.Add(time.Nanosecond), unit)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []*Bucket{}
+	for _, p := range ps {
+		b := byName[p.Name]
+		if b == nil {
+			b = &Bucket{Period: p}
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// Points returns the activity of each user in each bucket, ordered by bucket and then user. Users without
+// activity in a bucket have no point for it.
+func Points(buckets []*Bucket) []*Point {
+	result := []*Point{}
+	for _, b := range buckets {
+		users := map[string]*Point{}
+		point := func(u string) *Point {
+			if users[u] == nil {
+				users[u] = &Point{Bucket: b.Name, Since: b.Since.Format(dateForm), User: u}
+			}
+			return users[u]
+		}
+
+		for _, s := range b.PRs {
+			p := point(s.User)
+			p.PRs++
+			p.Delta += s.Delta
+		}
+		for _, s := range b.Reviews {
+			p := point(s.Reviewer)
+			p.Reviews++
+			p.ReviewComments += s.ReviewComments
+			p.ReviewWords += s.Words
+		}
+		for _, s := range b.Issues {
+			point(s.Closer).IssuesClosed++
+		}
+		for _, s := range b.Comments {
+			p := point(s.Commenter)
+			p.Comments += s.Comments
+			p.CommentWords += s.Words
+		}
+
+		ps := []*Point{}
+		for _, p := range users {
+			ps = append(ps, p)
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].User < ps[j].User })
+		result = append(result, ps...)
+	}
+	return result
+}